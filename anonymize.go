@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"net"
+)
+
+var (
+	ipv4Mask = net.CIDRMask(24, 32)
+	ipv6Mask = net.CIDRMask(48, 128)
+)
+
+// WithAnonymizeIP truncates IP addresses found in the named fields to
+// their /24 (IPv4) or /48 (IPv6) network, ports are kept.
+func WithAnonymizeIP(fields ...string) Option {
+	set := make(map[string]struct{}, len(fields))
+	for _, f := range fields {
+		set[f] = struct{}{}
+	}
+	return func(o *options) {
+		o.anonymizeIP = set
+	}
+}
+
+func anonymizeIPs(fields Fields, names map[string]struct{}) Fields {
+	var out Fields
+	for k, v := range fields {
+		if _, ok := names[k]; !ok || v == nil {
+			continue
+		}
+		if out == nil {
+			out = make(Fields, len(fields))
+			for k, v := range fields {
+				out[k] = v
+			}
+		}
+		out[k] = anonymizeIP(fmt.Sprint(v))
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+func anonymizeIP(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = addr, ""
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return addr
+	}
+	if v4 := ip.To4(); v4 != nil {
+		host = v4.Mask(ipv4Mask).String()
+	} else {
+		host = ip.Mask(ipv6Mask).String()
+	}
+	if port != "" {
+		return net.JoinHostPort(host, port)
+	}
+	return host
+}
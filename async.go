@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+type asyncEntry struct {
+	// logger may be derived from the one owning the queue, with its own
+	// output
+	logger *Logger
+	ctx    context.Context
+	lvl    Level
+	buf    []byte
+}
+
+type asyncWriter struct {
+	mu   sync.RWMutex
+	size int
+	ch   chan asyncEntry
+	// prio carries Error and above, ahead of ch and never dropped
+	prio   chan asyncEntry
+	closed bool
+	// done is closed before Close takes mu, releasing the senders waiting
+	// for room in prio
+	done    chan struct{}
+	wg      sync.WaitGroup
+	dropped uint64
+}
+
+// WithAsync makes New start a goroutine writing entries from a queue of
+// size entries, callers no longer wait for the output. Entries are
+// dropped while the queue is full, see Dropped, except Error and above:
+// they have a queue of their own, written first, and wait for room.
+// Close drains the queues.
+func WithAsync(size int) Option {
+	return func(o *options) {
+		o.async = &asyncWriter{size: size}
+	}
+}
+
+// Dropped returns the number of entries lost because the async queue was
+// full.
+func (l *Logger) Dropped() uint64 {
+	if a := l.opt.async; a != nil {
+		return atomic.LoadUint64(&a.dropped)
+	}
+	return 0
+}
+
+func (l *Logger) startAsync() {
+	a := l.opt.async
+	a.ch = make(chan asyncEntry, a.size)
+	a.prio = make(chan asyncEntry, a.size)
+	a.done = make(chan struct{})
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		ch, prio := a.ch, a.prio
+		for ch != nil || prio != nil {
+			var ae asyncEntry
+			var ok bool
+			select {
+			case ae, ok = <-prio:
+				if !ok {
+					prio = nil
+					continue
+				}
+			default:
+				select {
+				case ae, ok = <-prio:
+					if !ok {
+						prio = nil
+						continue
+					}
+				case ae, ok = <-ch:
+					if !ok {
+						ch = nil
+						continue
+					}
+				}
+			}
+			err := ae.logger.output(ae.ctx, ae.lvl, ae.buf)
+			if err != nil {
+				ae.logger.reportError(err)
+			}
+		}
+	}()
+	l.opt.closers = append(l.opt.closers, func() error {
+		close(a.done)
+		a.mu.Lock()
+		a.closed = true
+		close(a.ch)
+		close(a.prio)
+		a.mu.Unlock()
+		a.wg.Wait()
+		return nil
+	})
+}
+
+// enqueue hands e to the async writer and reports whether it took it,
+// after Close entries are written synchronously again. So are Error and
+// above entries whose context ends or whose logger is closed while they
+// wait for room.
+func (l *Logger) enqueue(e *Entry) bool {
+	a := l.opt.async
+	if a == nil {
+		return false
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed || a.ch == nil {
+		return false
+	}
+
+	ae := asyncEntry{logger: l, ctx: e.Context, lvl: e.Level, buf: append([]byte(nil), e.Buf.Bytes()...)}
+	if e.Level >= ErrorLevel {
+		var ctxDone <-chan struct{}
+		if e.Context != nil {
+			ctxDone = e.Context.Done()
+		}
+		select {
+		case a.prio <- ae:
+			return true
+		case <-ctxDone:
+			return false
+		case <-a.done:
+			return false
+		}
+	}
+	select {
+	case a.ch <- ae:
+	default:
+		atomic.AddUint64(&a.dropped, 1)
+	}
+	return true
+}
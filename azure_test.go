@@ -0,0 +1,83 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAzureMonitorSink(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte("shared-key"))
+	tests := []struct {
+		name       string
+		opts       []AzureOption
+		wantHeader map[string]string
+	}{
+		{
+			name:       "defaults",
+			wantHeader: map[string]string{"Log-Type": "App", "Content-Type": "application/json", "Time-Generated-Field": ""},
+		},
+		{
+			name:       "time generated field",
+			opts:       []AzureOption{WithTimeGeneratedField("time")},
+			wantHeader: map[string]string{"Time-Generated-Field": "time"},
+		},
+		{
+			name:       "sink options",
+			opts:       []AzureOption{WithAzureSinkOptions(WithRequestHeader("X-Test", "1"))},
+			wantHeader: map[string]string{"X-Test": "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &httpRecorder{}
+			srv := httptest.NewServer(rec)
+			defer srv.Close()
+
+			s, err := NewAzureMonitorSink("ws1", key, "App", append([]AzureOption{WithAzureEndpoint(srv.URL + "/api/logs")}, tt.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			s.now = func() time.Time { return now }
+
+			s.Write([]byte(`{"a":1}` + "\n"))
+			s.Write([]byte(`{"b":2}` + "\n"))
+			if err := s.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			got := rec.got()
+			if len(got) != 1 || got[0] != `[{"a":1},{"b":2}]` {
+				t.Fatalf("bodies %q, want one JSON array", got)
+			}
+
+			h := rec.header[0]
+			for k, v := range tt.wantHeader {
+				if h.Get(k) != v {
+					t.Errorf("header %s = %q, want %q", k, h.Get(k), v)
+				}
+			}
+			date := now.Format(http.TimeFormat)
+			mac := hmac.New(sha256.New, []byte("shared-key"))
+			mac.Write([]byte("POST\n" + strconv.Itoa(len(got[0])) + "\napplication/json\nx-ms-date:" + date + "\n/api/logs"))
+			if want := "SharedKey ws1:" + base64.StdEncoding.EncodeToString(mac.Sum(nil)); h.Get("Authorization") != want {
+				t.Errorf("Authorization = %q, want %q", h.Get("Authorization"), want)
+			}
+			if h.Get("X-Ms-Date") != date {
+				t.Errorf("x-ms-date = %q, want %q", h.Get("X-Ms-Date"), date)
+			}
+		})
+	}
+}
+
+func TestAzureMonitorSinkInvalidKey(t *testing.T) {
+	if _, err := NewAzureMonitorSink("ws1", "not base64!", "App"); err == nil {
+		t.Error("NewAzureMonitorSink accepted an invalid shared key")
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+)
+
+// WithBanner makes New log the startup banner, see Logger.Banner.
+func WithBanner() Option {
+	return func(o *options) {
+		o.banner = true
+	}
+}
+
+func Banner() {
+	std.bannerLogger().entry().write(InfoLevel, FmtEmptySeparate, "logger started")
+}
+
+// Banner logs a single entry describing the build, the runtime and the
+// logger configuration, regardless of the configured level.
+func (l *Logger) Banner() {
+	l.bannerLogger().entry().write(InfoLevel, FmtEmptySeparate, "logger started")
+}
+
+func (l *Logger) bannerLogger() *Logger {
+	return l.WithFields(l.bannerFields()).derive(func(o *options) { o.level = TraceLevel })
+}
+
+func (l *Logger) bannerFields() Fields {
+	fields := Fields{
+		"go_version": runtime.Version(),
+		"os":         runtime.GOOS,
+		"arch":       runtime.GOARCH,
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+		"num_cpu":    runtime.NumCPU(),
+		"pid":        os.Getpid(),
+		"log_level":  LevelMapping[l.opt.level],
+		"log_format": fmt.Sprintf("%T", l.opt.formatter),
+		"log_output": describeOutput(l.opt.position),
+	}
+	if host, err := os.Hostname(); err == nil {
+		fields["hostname"] = host
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		fields["module"] = info.Main.Path
+		fields["version"] = info.Main.Version
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision", "vcs.time", "vcs.modified":
+				fields[s.Key] = s.Value
+			}
+		}
+	}
+	return fields
+}
+
+func describeOutput(w interface{}) string {
+	switch out := w.(type) {
+	case *os.File:
+		return out.Name()
+	case *FileWriter:
+		return out.Path()
+	case *DecoratedWriter:
+		return describeOutput(out.W)
+	}
+	return fmt.Sprintf("%T", w)
+}
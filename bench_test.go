@@ -0,0 +1,41 @@
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+// assertNoAllocs fails b when fn allocates, disabled levels must be free.
+func assertNoAllocs(b *testing.B, fn func()) {
+	b.Helper()
+	if allocs := testing.AllocsPerRun(100, fn); allocs != 0 {
+		b.Fatalf("disabled call allocates %v times, want 0", allocs)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fn()
+	}
+}
+
+func BenchmarkDisabledDebug(b *testing.B) {
+	level := std.opt.level
+	SetOptions(WithLevel(InfoLevel))
+	defer SetOptions(WithLevel(level))
+	assertNoAllocs(b, func() { Debug("disabled") })
+}
+
+func BenchmarkDisabledLoggerDebug(b *testing.B) {
+	l := New(WithPosition(io.Discard), WithLevel(InfoLevel))
+	assertNoAllocs(b, func() { l.Debug("disabled", "call") })
+}
+
+func BenchmarkDisabledDebugMsg(b *testing.B) {
+	l := New(WithPosition(io.Discard), WithLevel(InfoLevel))
+	assertNoAllocs(b, func() { l.DebugMsg("disabled") })
+}
+
+func BenchmarkDisabledInfoKV(b *testing.B) {
+	l := New(WithPosition(io.Discard), WithLevel(WarnLevel))
+	assertNoAllocs(b, func() { l.InfoKV("disabled", "key", "value") })
+}
@@ -0,0 +1,21 @@
+package main
+
+import "unsafe"
+
+// WithZeroCopyWrite makes Logger.Write format the caller's bytes in place
+// instead of copying them. This is only safe while the formatter, hooks
+// and observers keep nothing referencing Entry.Args once Format returned:
+// the bytes belong to the caller, which may reuse them (bufio, log.Logger)
+// as soon as Write returns.
+func WithZeroCopyWrite() Option {
+	return func(o *options) {
+		o.zeroCopyWrite = true
+	}
+}
+
+func (l *Logger) bridgeString(data []byte) string {
+	if l.opt.zeroCopyWrite && len(data) > 0 {
+		return *(*string)(unsafe.Pointer(&data))
+	}
+	return string(data)
+}
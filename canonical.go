@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Canonical accumulates the fields of a unit of work, typically a request,
+// and logs them as a single wide entry once it completes. Its methods are
+// safe for concurrent use and do nothing on a nil *Canonical, so code can
+// call CanonicalFromContext(ctx).Set without checking.
+type Canonical struct {
+	mu      sync.Mutex
+	logger  *Logger
+	start   time.Time
+	fields  Fields
+	emitted bool
+}
+
+// Canonical starts a canonical log line, its latency is measured from now.
+func (l *Logger) Canonical() *Canonical {
+	return &Canonical{logger: l, start: l.now(), fields: Fields{}}
+}
+
+type canonicalKey struct{}
+
+func ContextWithCanonical(ctx context.Context, c *Canonical) context.Context {
+	return context.WithValue(ctx, canonicalKey{}, c)
+}
+
+// CanonicalFromContext returns the canonical line of ctx, or nil.
+func CanonicalFromContext(ctx context.Context) *Canonical {
+	c, _ := ctx.Value(canonicalKey{}).(*Canonical)
+	return c
+}
+
+func (c *Canonical) Set(key string, value any) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.fields[key] = value
+	c.mu.Unlock()
+}
+
+func (c *Canonical) SetFields(fields Fields) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	for k, v := range fields {
+		c.fields[k] = v
+	}
+	c.mu.Unlock()
+}
+
+// Add increments the counter key, e.g. cache_hits, by delta.
+func (c *Canonical) Add(key string, delta int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	n, _ := c.fields[key].(int64)
+	c.fields[key] = n + delta
+	c.mu.Unlock()
+}
+
+// AddDuration adds d to the time accumulated under key, e.g. db_time.
+func (c *Canonical) AddDuration(key string, d time.Duration) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	total, _ := c.fields[key].(time.Duration)
+	c.fields[key] = total + d
+	c.mu.Unlock()
+}
+
+// Emit logs msg at lvl with the accumulated fields and the Latency fields
+// since the line was started. Only the first call logs, later ones are
+// ignored.
+func (c *Canonical) Emit(lvl Level, msg string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	if c.emitted {
+		c.mu.Unlock()
+		return
+	}
+	c.emitted = true
+	fields := make(Fields, len(c.fields)+2)
+	for k, v := range c.fields {
+		fields[k] = v
+	}
+	c.mu.Unlock()
+	for k, v := range Latency(c.start, c.logger.now()) {
+		fields[k] = v
+	}
+
+	if c.logger.enabled(lvl) {
+		c.logger.WithFields(fields).entry().write(lvl, FmtEmptySeparate, msg)
+	}
+}
+
+// Middleware wraps next so every request gets a canonical line, reachable
+// by handlers through CanonicalFromContext and logged when next returns
+// with the request fields, status and bytes written. Server errors are
+// logged at ErrorLevel.
+func (l *Logger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := l.Canonical()
+		c.SetFields(HTTPRequest(r))
+		rw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			c.SetFields(Fields{"http.status": rw.status, "http.bytes_written": rw.written})
+			lvl := InfoLevel
+			if rw.status >= http.StatusInternalServerError {
+				lvl = ErrorLevel
+			}
+			c.Emit(lvl, "canonical-log-line")
+		}()
+		next.ServeHTTP(rw, r.WithContext(ContextWithCanonical(r.Context(), c)))
+	})
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status  int
+	written int64
+	wrote   bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wrote {
+		w.status, w.wrote = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	w.wrote = true
+	n, err := w.ResponseWriter.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush and Hijack forward to the wrapped writer so streaming and
+// websocket handlers keep working behind the middleware.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wrote = true
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("logie: response writer does not support hijacking")
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.wrote = true
+	}
+	return conn, rw, err
+}
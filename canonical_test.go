@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func canonicalEntries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var entries []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var m map[string]any
+		if err := decodeJSON(line, &m); err != nil {
+			t.Fatalf("decode %s: %v", line, err)
+		}
+		entries = append(entries, m)
+	}
+	return entries
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		wantLevel string
+		status    float64
+		written   float64
+	}{
+		{
+			name:      "implicit ok",
+			handler:   func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hello")) },
+			wantLevel: "Info",
+			status:    200,
+			written:   5,
+		},
+		{
+			name: "server error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+				w.WriteHeader(http.StatusOK)
+			},
+			wantLevel: "Error",
+			status:    502,
+		},
+		{
+			name: "fields from the handler",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				c := CanonicalFromContext(r.Context())
+				c.Set("user", "ana")
+				c.Add("cache_hits", 2)
+				w.WriteHeader(http.StatusNotFound)
+			},
+			wantLevel: "Info",
+			status:    404,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := New(WithPosition(&buf), WithFormatter(&JSONFormatter{}))
+			l.Middleware(tt.handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/x", nil))
+
+			entries := canonicalEntries(t, &buf)
+			if len(entries) != 1 {
+				t.Fatalf("logged %d entries, want 1", len(entries))
+			}
+			e := entries[0]
+			if e["level"] != tt.wantLevel || e["http.status"] != tt.status || e["http.bytes_written"] != tt.written {
+				t.Errorf("entry %v, want level %s status %v written %v", e, tt.wantLevel, tt.status, tt.written)
+			}
+			if _, ok := e["latency"].(string); !ok {
+				t.Errorf("latency = %#v, want the string of Latency", e["latency"])
+			}
+			if _, ok := e["latency_ms"].(float64); !ok {
+				t.Errorf("latency_ms = %#v, want a number", e["latency_ms"])
+			}
+		})
+	}
+}
+
+func TestMiddlewareFlush(t *testing.T) {
+	l := New(WithPosition(&bytes.Buffer{}))
+	rec := httptest.NewRecorder()
+	l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("response writer is not a Flusher")
+		}
+		f.Flush()
+	})).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if !rec.Flushed {
+		t.Error("Flush was not forwarded")
+	}
+}
+
+func TestMiddlewareHijack(t *testing.T) {
+	l := New(WithPosition(&bytes.Buffer{}))
+	srv := httptest.NewServer(l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, ok := w.(http.Hijacker)
+		if !ok {
+			t.Error("response writer is not a Hijacker")
+			return
+		}
+		conn, rw, err := h.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+	})))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: x\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n"))
+	status, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || !strings.Contains(status, "101") {
+		t.Errorf("status line %q, %v, want 101 from the hijacked connection", status, err)
+	}
+}
+
+func TestCanonicalEmitOnce(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(WithPosition(&buf), WithFormatter(&JSONFormatter{}))
+	c := l.Canonical()
+	c.AddDuration("db_time", time.Millisecond)
+	c.Emit(InfoLevel, "done")
+	c.Emit(InfoLevel, "again")
+	var nilLine *Canonical
+	nilLine.Emit(InfoLevel, "nothing")
+	if n := len(canonicalEntries(t, &buf)); n != 1 {
+		t.Errorf("logged %d entries, want 1", n)
+	}
+}
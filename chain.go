@@ -0,0 +1,70 @@
+package main
+
+import "fmt"
+
+// FormatterFunc adapts a function to a Formatter.
+type FormatterFunc func(e *Entry) error
+
+func (f FormatterFunc) Format(e *Entry) error {
+	return f(e)
+}
+
+// Format applies t to e, so transforms can be steps of ChainFormatter.
+func (t Transform) Format(e *Entry) error {
+	e.ownCopy()
+	t(e)
+	return nil
+}
+
+type chainFormatter []Formatter
+
+// ChainFormatter runs steps in order on the same entry, stopping at the
+// first error. Steps before the encoder rewrite the entry, e.g. the
+// transforms RedactFields or DropFields, steps after it rewrite the
+// encoded Buf, e.g. PrefixFormatter.
+func ChainFormatter(steps ...Formatter) Formatter {
+	return chainFormatter(steps)
+}
+
+func (c chainFormatter) Format(e *Entry) error {
+	for _, f := range c {
+		if err := f.Format(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrefixFormatter inserts the prefix returned by fn before the encoded
+// entry.
+func PrefixFormatter(fn func(e *Entry) string) Formatter {
+	return FormatterFunc(func(e *Entry) error {
+		prefix := fn(e)
+		if prefix == "" {
+			return nil
+		}
+		out := make([]byte, 0, len(prefix)+e.Buf.Len())
+		out = append(append(out, prefix...), e.Buf.Bytes()...)
+		e.Buf.Reset()
+		e.Buf.Write(out)
+		return nil
+	})
+}
+
+var syslogSeverity = map[Level]int{
+	TraceLevel: 7,
+	DebugLevel: 7,
+	InfoLevel:  6,
+	WarnLevel:  4,
+	ErrorLevel: 3,
+	PanicLevel: 2,
+	FatalLevel: 1,
+}
+
+// SyslogPriority prefixes the encoded entry with the syslog priority
+// header <PRI> of facility and the entry level, e.g. <14> for user.info.
+func SyslogPriority(facility int) Formatter {
+	return PrefixFormatter(func(e *Entry) string {
+		return fmt.Sprintf("<%d>", facility*8+syslogSeverity[e.Level])
+	})
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CheckpointReader reads the entries of a log file, newline delimited or
+// framed by FramedWriter, and persists the offset of the last
+// committed entry so a consumer resumes where it stopped after a restart.
+// The checkpoint also identifies the file by a checksum of its first
+// bytes, so a file replaced by rotation is read from the start.
+type CheckpointReader struct {
+	fd         *os.File
+	r          *bufio.Reader
+	checkpoint string
+	framed     bool
+	offset     int64
+	pending    int64
+}
+
+// checkpointIdentity is the number of leading bytes identifying a file.
+const checkpointIdentity = 256
+
+// NewCheckpointReader opens path and seeks to the offset stored in
+// checkpoint. A file shorter than that offset is assumed to be truncated,
+// and one whose first bytes changed to be a new file, both are read from
+// the start.
+func NewCheckpointReader(path, checkpoint string, framed bool) (*CheckpointReader, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	cr := &CheckpointReader{fd: fd, checkpoint: checkpoint, framed: framed}
+
+	if data, err := os.ReadFile(checkpoint); err == nil {
+		off, n, sum, err := parseCheckpoint(string(data))
+		if err != nil {
+			_ = fd.Close()
+			return nil, fmt.Errorf("logie: invalid checkpoint %s: %w", checkpoint, err)
+		}
+		if fi, err := fd.Stat(); err == nil && off <= fi.Size() {
+			// checkpoints written before the identity was stored have n == -1
+			if gotN, gotSum, err := fileIdentity(fd, off); n < 0 || err == nil && gotN == n && gotSum == sum {
+				cr.offset = off
+			}
+		}
+	}
+	if _, err := fd.Seek(cr.offset, io.SeekStart); err != nil {
+		_ = fd.Close()
+		return nil, err
+	}
+	cr.r = bufio.NewReader(fd)
+	cr.pending = cr.offset
+	return cr, nil
+}
+
+// Next returns the next entry, io.EOF means no complete entry is
+// available yet, Next may be called again once the file grew.
+func (cr *CheckpointReader) Next() ([]byte, error) {
+	if cr.framed {
+		return cr.nextFrame()
+	}
+	line, err := cr.r.ReadBytes('\n')
+	if err == io.EOF && len(line) > 0 {
+		// incomplete line, read it again once it is terminated
+		return nil, cr.rewind()
+	}
+	if err != nil {
+		return nil, err
+	}
+	cr.pending += int64(len(line))
+	return bytes.TrimSuffix(line, []byte("\n")), nil
+}
+
+func (cr *CheckpointReader) nextFrame() ([]byte, error) {
+	n := 0
+	size, err := binary.ReadUvarint(byteCounter{cr.r, &n})
+	if err == io.EOF {
+		return nil, io.EOF
+	} else if err == io.ErrUnexpectedEOF {
+		return nil, cr.rewind()
+	} else if err != nil {
+		return nil, err
+	}
+	if size > maxFrameSize {
+		return nil, errFrameTooLarge
+	}
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(cr.r, frame); err != nil {
+		return nil, cr.rewind()
+	}
+	cr.pending += int64(n) + int64(size)
+	return frame, nil
+}
+
+// rewind drops a partially read entry so the next call starts over.
+func (cr *CheckpointReader) rewind() error {
+	if _, err := cr.fd.Seek(cr.pending, io.SeekStart); err != nil {
+		return err
+	}
+	cr.r.Reset(cr.fd)
+	return io.EOF
+}
+
+// Offset returns the position following the last entry returned by Next.
+func (cr *CheckpointReader) Offset() int64 {
+	return cr.pending
+}
+
+// Commit atomically persists the offset of the entries read so far.
+func (cr *CheckpointReader) Commit() error {
+	if cr.pending == cr.offset {
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(cr.checkpoint), filepath.Base(cr.checkpoint)+".*")
+	if err != nil {
+		return err
+	}
+	n, sum, err := fileIdentity(cr.fd, cr.pending)
+	if err == nil {
+		_, err = fmt.Fprintf(tmp, "%d %d %d\n", cr.pending, n, sum)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), cr.checkpoint)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	cr.offset = cr.pending
+	return nil
+}
+
+func (cr *CheckpointReader) Close() error {
+	return cr.fd.Close()
+}
+
+// parseCheckpoint parses "offset length checksum", or a lone offset with
+// length -1.
+func parseCheckpoint(s string) (off, n int64, sum uint32, err error) {
+	fields := strings.Fields(s)
+	if len(fields) != 1 && len(fields) != 3 {
+		return 0, 0, 0, fmt.Errorf("want 1 or 3 fields, got %d", len(fields))
+	}
+	if off, err = strconv.ParseInt(fields[0], 10, 64); err != nil {
+		return 0, 0, 0, err
+	}
+	if len(fields) == 1 {
+		return off, -1, 0, nil
+	}
+	if n, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+		return 0, 0, 0, err
+	}
+	s32, err := strconv.ParseUint(fields[2], 10, 32)
+	return off, n, uint32(s32), err
+}
+
+// fileIdentity returns the length and checksum of the first bytes of fd,
+// up to off, which have already been read and cannot change while the
+// file is appended to.
+func fileIdentity(fd *os.File, off int64) (int64, uint32, error) {
+	n := off
+	if n > checkpointIdentity {
+		n = checkpointIdentity
+	}
+	buf := make([]byte, n)
+	if _, err := fd.ReadAt(buf, 0); err != nil {
+		return 0, 0, err
+	}
+	return n, crc32.ChecksumIEEE(buf), nil
+}
+
+// byteCounter counts the bytes consumed by binary.ReadUvarint.
+type byteCounter struct {
+	r io.ByteReader
+	n *int
+}
+
+func (b byteCounter) ReadByte() (byte, error) {
+	c, err := b.r.ReadByte()
+	if err == nil {
+		*b.n++
+	}
+	return c, err
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readAll(t *testing.T, cr *CheckpointReader, n int) []string {
+	t.Helper()
+	var got []string
+	for i := 0; i < n; i++ {
+		line, err := cr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(line))
+	}
+	return got
+}
+
+func TestCheckpointReaderResumes(t *testing.T) {
+	tests := []struct {
+		name  string
+		first string
+		// replace, when set, rewrites the log before resuming
+		replace func(path string) error
+		want    []string
+	}{
+		{
+			name:  "appended",
+			first: "a\nb\n",
+			replace: func(path string) error {
+				f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				_, err = f.WriteString("c\n")
+				return err
+			},
+			want: []string{"c"},
+		},
+		{
+			name:  "truncated",
+			first: "aaaa\nbbbb\n",
+			replace: func(path string) error {
+				return os.WriteFile(path, []byte("c\n"), 0o644)
+			},
+			want: []string{"c"},
+		},
+		{
+			name:  "rotated to a longer file",
+			first: "a\nb\n",
+			replace: func(path string) error {
+				if err := os.Rename(path, path+".1"); err != nil {
+					return err
+				}
+				return os.WriteFile(path, []byte("new1\nnew2\n"), 0o644)
+			},
+			want: []string{"new1", "new2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path, ckpt := filepath.Join(dir, "app.log"), filepath.Join(dir, "app.ckpt")
+			if err := os.WriteFile(path, []byte(tt.first), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			cr, err := NewCheckpointReader(path, ckpt, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			readAll(t, cr, 10)
+			if err := cr.Commit(); err != nil {
+				t.Fatal(err)
+			}
+			cr.Close()
+
+			if err := tt.replace(path); err != nil {
+				t.Fatal(err)
+			}
+			cr, err = NewCheckpointReader(path, ckpt, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cr.Close()
+			if got := readAll(t, cr, 10); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("resumed with %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckpointReaderLegacyOffset(t *testing.T) {
+	dir := t.TempDir()
+	path, ckpt := filepath.Join(dir, "app.log"), filepath.Join(dir, "app.ckpt")
+	os.WriteFile(path, []byte("a\nb\n"), 0o644)
+	os.WriteFile(ckpt, []byte("2\n"), 0o644)
+	cr, err := NewCheckpointReader(path, ckpt, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cr.Close()
+	if got := readAll(t, cr, 10); len(got) != 1 || got[0] != "b" {
+		t.Errorf("resumed with %q, want [b]", got)
+	}
+
+	os.WriteFile(ckpt, []byte("2 x 3\n"), 0o644)
+	if _, err := NewCheckpointReader(path, ckpt, false); err == nil {
+		t.Error("invalid checkpoint accepted")
+	}
+}
+
+func TestCheckpointReaderPartialLine(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	os.WriteFile(path, []byte("a\npart"), 0o644)
+	cr, err := NewCheckpointReader(path, filepath.Join(dir, "app.ckpt"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cr.Close()
+	if got := readAll(t, cr, 10); len(got) != 1 {
+		t.Fatalf("read %q before the line was terminated", got)
+	}
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString("ial\n")
+	f.Close()
+	if got := readAll(t, cr, 10); len(got) != 1 || got[0] != "partial" {
+		t.Errorf("read %q, want [partial]", got)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// childFDEnv names the descriptor of the pipe a child process writes its
+// entries to, it is set by StartChild.
+const childFDEnv = "LOGIE_PARENT_FD"
+
+// NewChild returns a logger forwarding every entry to the parent process
+// when it was started by StartChild, and false otherwise. The parent
+// applies its own level and output, opts may add fields or options.
+func NewChild(opts ...Option) (*Logger, bool) {
+	fd, err := strconv.Atoi(os.Getenv(childFDEnv))
+	if err != nil || fd < 3 {
+		return nil, false
+	}
+	pipe := os.NewFile(uintptr(fd), "logie-parent")
+	if pipe == nil {
+		return nil, false
+	}
+	return New(append([]Option{
+		WithPosition(Frame(pipe)),
+		WithLevel(TraceLevel),
+		WithFormatter(&JSONFormatter{}),
+	}, opts...)...), true
+}
+
+// StartChild starts cmd with a pipe inherited as an extra file, entries
+// the child logs through NewChild are read back and written by l with a
+// child_pid field. wait waits for the child to exit and for its entries
+// to be drained.
+func (l *Logger) StartChild(cmd *exec.Cmd) (wait func() error, err error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.ExtraFiles = append(cmd.ExtraFiles, w)
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d", childFDEnv, 2+len(cmd.ExtraFiles)))
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer r.Close()
+		done <- l.ingestChild(cmd.Process.Pid, r)
+	}()
+	return func() error {
+		werr := cmd.Wait()
+		if ierr := <-done; werr == nil {
+			werr = ierr
+		}
+		return werr
+	}, nil
+}
+
+func (l *Logger) ingestChild(pid int, r io.Reader) error {
+	logger := l.derive(func(o *options) {
+		o.disableCaller = true
+	}).WithFields(Fields{"child_pid": pid})
+
+	fr := NewFrameReader(r)
+	for {
+		frame, err := fr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		var m map[string]any
+		if err := decodeJSON(frame, &m); err != nil {
+			l.reportError(fmt.Errorf("logie: child %d: %w", pid, err))
+			continue
+		}
+
+		lvl := l.opt.stdLevel
+		if s, ok := m["level"].(string); ok {
+			_ = lvl.UnmarshalText([]byte(s))
+		}
+		msg, _ := m["message"].(string)
+		for k := range reservedKeys {
+			delete(m, k)
+		}
+		if logger.enabled(lvl) {
+			logger.WithFields(m).entry().write(lvl, FmtEmptySeparate, msg)
+		}
+	}
+}
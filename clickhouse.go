@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+type ClickHouseOption func(*clickHouseConfig)
+
+type clickHouseConfig struct {
+	columns  map[string]string
+	extra    string
+	user     string
+	password string
+	settings url.Values
+	sinkOpts []HTTPSinkOption
+}
+
+// WithColumns maps table columns to entry fields, basic fields included,
+// e.g. {"ts": "time", "user_id": "user.id"}. It replaces the default
+// time, level, logger and message columns.
+func WithColumns(columns map[string]string) ClickHouseOption {
+	return func(c *clickHouseConfig) {
+		c.columns = columns
+	}
+}
+
+// WithExtraColumn stores the fields not mapped to a column as a JSON
+// string in column, "fields" by default, an empty name drops them.
+func WithExtraColumn(column string) ClickHouseOption {
+	return func(c *clickHouseConfig) {
+		c.extra = column
+	}
+}
+
+func WithClickHouseAuth(user, password string) ClickHouseOption {
+	return func(c *clickHouseConfig) {
+		c.user, c.password = user, password
+	}
+}
+
+// WithClickHouseSetting passes a query setting, e.g. async_insert=1.
+func WithClickHouseSetting(key, value string) ClickHouseOption {
+	return func(c *clickHouseConfig) {
+		c.settings.Set(key, value)
+	}
+}
+
+func WithClickHouseSinkOptions(opts ...HTTPSinkOption) ClickHouseOption {
+	return func(c *clickHouseConfig) {
+		c.sinkOpts = append(c.sinkOpts, opts...)
+	}
+}
+
+// NewClickHouseSink batches JSON entries into INSERT statements sent to
+// the HTTP interface of ClickHouse at baseURL, e.g.
+// "http://clickhouse:8123", as JSONEachRow rows of table. The native
+// protocol is not supported.
+func NewClickHouseSink(baseURL, table string, opts ...ClickHouseOption) (*HTTPSink, error) {
+	if !sqlIdent.MatchString(strings.Replace(table, ".", "_", 1)) {
+		return nil, fmt.Errorf("logie: invalid table name %q", table)
+	}
+	c := &clickHouseConfig{
+		columns: map[string]string{
+			"time":    "time",
+			"level":   "level",
+			"logger":  "logger",
+			"message": "message",
+		},
+		extra:    "fields",
+		settings: url.Values{"date_time_input_format": {"best_effort"}},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	q := url.Values{}
+	for k, v := range c.settings {
+		q[k] = v
+	}
+	q.Set("query", "INSERT INTO "+table+" FORMAT JSONEachRow")
+	sinkOpts := []HTTPSinkOption{WithRequestHeader("Content-Type", "application/x-ndjson")}
+	if c.user != "" {
+		sinkOpts = append(sinkOpts,
+			WithRequestHeader("X-ClickHouse-User", c.user),
+			WithRequestHeader("X-ClickHouse-Key", c.password))
+	}
+	s := NewHTTPSink(strings.TrimSuffix(baseURL, "/")+"/?"+q.Encode(), append(sinkOpts, c.sinkOpts...)...)
+	s.wrap = c.row
+	return s, nil
+}
+
+// row maps a JSON entry to the columns of the table, entries which are not
+// JSON become the message.
+func (c *clickHouseConfig) row(entry []byte) []byte {
+	var m map[string]any
+	if err := decodeJSON(entry, &m); err != nil {
+		m = map[string]any{"message": string(entry)}
+	}
+	row := make(map[string]any, len(c.columns)+1)
+	for col, field := range c.columns {
+		if v, ok := m[field]; ok {
+			row[col] = v
+			delete(m, field)
+		}
+	}
+	if c.extra != "" {
+		delete(m, "schema_version")
+		if len(m) > 0 {
+			extra, _ := json.Marshal(m)
+			row[c.extra] = string(extra)
+		}
+	}
+	b, _ := json.Marshal(row)
+	return b
+}
@@ -0,0 +1,34 @@
+package main
+
+// Clone returns a deep copy of e safe to keep and use from another
+// goroutine after e was released, e.g. by asynchronous hooks.
+func (e *Entry) Clone() *Entry {
+	c := e.copyEntry()
+	c.Buf.Write(e.Buf.Bytes())
+	for k, v := range e.Map {
+		c.Map[k] = v
+	}
+	return c
+}
+
+// SetField sets a field of e, copying Fields first when it is still
+// shared with the logger.
+func (e *Entry) SetField(key string, value any) {
+	e.ownCopy()
+	e.Fields[key] = value
+}
+
+func (e *Entry) DeleteField(key string) {
+	if _, ok := e.Fields[key]; !ok {
+		return
+	}
+	e.ownCopy()
+	delete(e.Fields, key)
+}
+
+func (e *Entry) ownCopy() {
+	if !e.ownFields {
+		e.Fields = mergeFields(nil, e.Fields)
+		e.ownFields = true
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"sort"
+	"sync"
+)
+
+// CodeInfo documents an event code, see RegisterCode.
+type CodeInfo struct {
+	Description string
+	URL         string
+}
+
+var (
+	codesMu sync.RWMutex
+	codes   = map[string]CodeInfo{}
+)
+
+// RegisterCode documents code for support teams, entries logged with it
+// carry info.URL as code_url. Registering a code twice replaces it.
+func RegisterCode(code string, info CodeInfo) {
+	codesMu.Lock()
+	codes[code] = info
+	codesMu.Unlock()
+}
+
+// LookupCode returns what RegisterCode recorded for code.
+func LookupCode(code string) (CodeInfo, bool) {
+	codesMu.RLock()
+	defer codesMu.RUnlock()
+	info, ok := codes[code]
+	return info, ok
+}
+
+// Codes returns the registered codes, sorted, e.g. to generate docs.
+func Codes() []string {
+	codesMu.RLock()
+	defer codesMu.RUnlock()
+	list := make([]string, 0, len(codes))
+	for code := range codes {
+		list = append(list, code)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Code returns a logger whose entries carry the stable event code, as in
+// l.Code("AUTH001").Warn("login failed"), so runbooks can key off codes
+// instead of message text.
+func (l *Logger) Code(code string) *Logger {
+	fields := Fields{"code": code}
+	if info, ok := LookupCode(code); ok && info.URL != "" {
+		fields["code_url"] = info.URL
+	}
+	return l.WithFields(fields)
+}
+
+func Code(code string) *Logger {
+	return std.Code(code)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"sort"
+	"sync"
+)
+
+// maxDictSize is the DEFLATE window, bytes of a longer dictionary are
+// never referenced.
+const maxDictSize = 32 << 10
+
+// CompressedWriter compresses every write, one entry, into its own DEFLATE
+// frame prefixed with its length as an unsigned varint, see FrameReader.
+// Independent frames survive reconnects of a streaming network writer
+// but compress poorly on their own, a dictionary holding the repeated
+// JSON keys makes up for it. DEFLATE is used as zstd is not part of the
+// standard library.
+type CompressedWriter struct {
+	mu    sync.Mutex
+	w     io.Writer
+	dict  []byte
+	level int
+	fw    *flate.Writer
+	buf   bytes.Buffer
+}
+
+type CompressOption func(*CompressedWriter)
+
+// WithDictionary sets the preset dictionary shared with the reader, see
+// SchemaDictionary and TrainDictionary.
+func WithDictionary(dict []byte) CompressOption {
+	return func(c *CompressedWriter) {
+		if len(dict) > maxDictSize {
+			dict = dict[len(dict)-maxDictSize:]
+		}
+		c.dict = dict
+	}
+}
+
+func WithCompressionLevel(level int) CompressOption {
+	return func(c *CompressedWriter) {
+		c.level = level
+	}
+}
+
+func NewCompressedWriter(w io.Writer, opts ...CompressOption) (*CompressedWriter, error) {
+	c := &CompressedWriter{w: w, level: flate.DefaultCompression}
+	for _, opt := range opts {
+		opt(c)
+	}
+	fw, err := flate.NewWriterDict(&c.buf, c.level, c.dict)
+	if err != nil {
+		return nil, err
+	}
+	c.fw = fw
+	return c, nil
+}
+
+func (c *CompressedWriter) Write(p []byte) (int, error) {
+	return c.WriteLevel(InfoLevel, p)
+}
+
+// WriteLevel compresses p and hands the frame to the underlying writer,
+// with lvl when it is a LevelWriter.
+func (c *CompressedWriter) WriteLevel(lvl Level, p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var hdr [binary.MaxVarintLen64]byte
+	c.buf.Reset()
+	c.buf.Write(hdr[:])
+	c.fw.Reset(&c.buf)
+	if _, err := c.fw.Write(p); err != nil {
+		return 0, err
+	}
+	if err := c.fw.Close(); err != nil {
+		return 0, err
+	}
+
+	// the length goes right before the payload in the reserved header
+	frame := c.buf.Bytes()
+	n := binary.PutUvarint(hdr[:], uint64(len(frame)-len(hdr)))
+	frame = frame[len(hdr)-n:]
+	copy(frame, hdr[:n])
+	if err := writeFull(c.w, lvl, frame); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// CompressedReader reads back the entries of a CompressedWriter.
+type CompressedReader struct {
+	fr   *FrameReader
+	dict []byte
+	zr   io.ReadCloser
+}
+
+func NewCompressedReader(r io.Reader, dict []byte) *CompressedReader {
+	if len(dict) > maxDictSize {
+		dict = dict[len(dict)-maxDictSize:]
+	}
+	return &CompressedReader{fr: NewFrameReader(r), dict: dict}
+}
+
+// Next returns the next entry, io.EOF after the last one.
+func (cr *CompressedReader) Next() ([]byte, error) {
+	frame, err := cr.fr.Next()
+	if err != nil {
+		return nil, err
+	}
+	if cr.zr == nil {
+		cr.zr = flate.NewReaderDict(bytes.NewReader(frame), cr.dict)
+	} else if err := cr.zr.(flate.Resetter).Reset(bytes.NewReader(frame), cr.dict); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(cr.zr)
+}
+
+// SchemaDictionary builds a dictionary from the field keys of a schema
+// and the basic fields of JSONFormatter, as they appear in its output.
+func SchemaDictionary(keys ...string) []byte {
+	var buf bytes.Buffer
+	for _, k := range keys {
+		buf.WriteString(`"` + k + `":`)
+	}
+	// DEFLATE references recent bytes more cheaply, put the fields of
+	// every entry last
+	buf.WriteString(`{"schema_version":"` + SchemaVersion + `","level":"Info","time":"","file":"","func":"","message":""}`)
+	return buf.Bytes()
+}
+
+// TrainDictionary builds a dictionary of at most size bytes from sample
+// entries: their most frequent JSON tokens, most frequent last.
+func TrainDictionary(samples [][]byte, size int) []byte {
+	counts := make(map[string]int)
+	for _, s := range samples {
+		for _, tok := range jsonTokens(s) {
+			counts[tok]++
+		}
+	}
+	tokens := make([]string, 0, len(counts))
+	for tok, n := range counts {
+		if n > 1 {
+			tokens = append(tokens, tok)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		wi, wj := counts[tokens[i]]*len(tokens[i]), counts[tokens[j]]*len(tokens[j])
+		if wi != wj {
+			return wi > wj
+		}
+		return tokens[i] < tokens[j]
+	})
+
+	if size > maxDictSize {
+		size = maxDictSize
+	}
+	var picked []string
+	total := 0
+	for _, tok := range tokens {
+		if total+len(tok) > size {
+			continue
+		}
+		picked = append(picked, tok)
+		total += len(tok)
+	}
+	var buf bytes.Buffer
+	for i := len(picked) - 1; i >= 0; i-- {
+		buf.WriteString(picked[i])
+	}
+	return buf.Bytes()
+}
+
+// jsonTokens splits a JSON line on the separators between keys and
+// values, keeping them attached so tokens look like `"key":"value",`.
+func jsonTokens(line []byte) []string {
+	var tokens []string
+	start := 0
+	inString := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			inString = !inString
+		case ',', ':', '{', '}':
+			if !inString {
+				tokens = append(tokens, string(line[start:i+1]))
+				start = i + 1
+			}
+		}
+	}
+	return tokens
+}
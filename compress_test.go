@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestCompressedWriterRoundTrip(t *testing.T) {
+	entries := []string{`{"message":"a"}` + "\n", `{"message":"bb"}` + "\n", ""}
+	tests := []struct {
+		name string
+		dict []byte
+	}{
+		{"no dictionary", nil},
+		{"schema dictionary", SchemaDictionary("user", "request_id")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewCompressedWriter(&buf, WithDictionary(tt.dict))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range entries {
+				if n, err := w.Write([]byte(e)); err != nil || n != len(e) {
+					t.Fatalf("Write(%q) = %d, %v", e, n, err)
+				}
+			}
+			r := NewCompressedReader(&buf, tt.dict)
+			for _, want := range entries {
+				got, err := r.Next()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != want {
+					t.Errorf("Next() = %q, want %q", got, want)
+				}
+			}
+			if _, err := r.Next(); !errors.Is(err, io.EOF) {
+				t.Errorf("Next() after the last entry error = %v, want io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestCompressedWriterKeepsLevel(t *testing.T) {
+	rec := &levelRecorder{}
+	w, err := NewCompressedWriter(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := New(WithPosition(w))
+	l.Error("broken")
+	if len(rec.levels) != 1 || rec.levels[0] != ErrorLevel {
+		t.Errorf("levels = %v, want [Error]", rec.levels)
+	}
+}
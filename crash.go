@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+)
+
+// crashReportLimit caps the crash output logged by ReportCrash.
+const crashReportLimit = 64 << 10
+
+// ReportCrash logs a "process crashed" entry when the crash file at path,
+// see CaptureCrashes, holds the output of a previous fatal crash, then
+// truncates it. It reports whether a crash was found.
+func (l *Logger) ReportCrash(path string) (bool, error) {
+	out, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) || err == nil && len(out) == 0 {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	// the same keys as PanicValue, so one query finds both
+	fields := Fields{"crash_file": path, "panic": true}
+	if st, err := os.Stat(path); err == nil {
+		fields["crashed_at"] = st.ModTime()
+	}
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	if sc.Scan() {
+		fields["panic_value"] = sc.Text()
+	}
+	if len(out) > crashReportLimit {
+		out = out[:crashReportLimit]
+	}
+	fields["stack"] = string(out)
+
+	l.derive(func(o *options) {
+		o.level, o.disableCaller = TraceLevel, true
+	}).WithFields(fields).entry().write(ErrorLevel, FmtEmptySeparate, "process crashed")
+	return true, os.Truncate(path, 0)
+}
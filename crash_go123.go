@@ -0,0 +1,24 @@
+//go:build go1.23
+
+package main
+
+import (
+	"os"
+	"runtime/debug"
+)
+
+// CaptureCrashes reports a crash left in the file at path by a previous
+// run, see ReportCrash, then makes the runtime copy the output of fatal
+// crashes, which bypass the logger, to that file.
+func (l *Logger) CaptureCrashes(path string) error {
+	if _, err := l.ReportCrash(path); err != nil {
+		return err
+	}
+	fd, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	// the runtime keeps its own duplicate of the descriptor
+	return debug.SetCrashOutput(fd, debug.CrashOptions{})
+}
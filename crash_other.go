@@ -0,0 +1,14 @@
+//go:build !go1.23
+
+package main
+
+import "errors"
+
+// CaptureCrashes needs debug.SetCrashOutput from Go 1.23, older
+// toolchains still report crashes recorded by other means.
+func (l *Logger) CaptureCrashes(path string) error {
+	if _, err := l.ReportCrash(path); err != nil {
+		return err
+	}
+	return errors.New("logie: crash capture requires Go 1.23")
+}
@@ -0,0 +1,191 @@
+package main
+
+import "context"
+
+type (
+	ctxFieldsKey struct{}
+	ctxLevelKey  struct{}
+)
+
+// ContextWithFields returns a copy of ctx carrying fields, they are added
+// to entries logged through the *Ctx methods.
+func ContextWithFields(ctx context.Context, fields Fields) context.Context {
+	merged := make(Fields, len(fields))
+	for k, v := range FieldsFromContext(ctx) {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, ctxFieldsKey{}, merged)
+}
+
+func FieldsFromContext(ctx context.Context) Fields {
+	fields, _ := ctx.Value(ctxFieldsKey{}).(Fields)
+	return fields
+}
+
+// ContextWithLevel overrides the logger level for entries logged with
+// ctx through the *Ctx methods, e.g. to debug a single request.
+func ContextWithLevel(ctx context.Context, lvl Level) context.Context {
+	return context.WithValue(ctx, ctxLevelKey{}, lvl)
+}
+
+func LevelFromContext(ctx context.Context) (Level, bool) {
+	lvl, ok := ctx.Value(ctxLevelKey{}).(Level)
+	return lvl, ok
+}
+
+func (l *Logger) levelFor(ctx context.Context) Level {
+	return l.shedLevel(l.baseLevel(ctx))
+}
+
+func (l *Logger) baseLevel(ctx context.Context) Level {
+	if ctx != nil {
+		if lvl, ok := LevelFromContext(ctx); ok {
+			return lvl
+		}
+		if fn := l.opt.dynamicLevel; fn != nil {
+			return fn(ctx)
+		}
+	}
+	return l.opt.level
+}
+
+func (l *Logger) enabledCtx(ctx context.Context, lvl Level) bool {
+	return l.levelFor(ctx) <= lvl
+}
+
+// WithContextExtractor registers fn to pull extra fields such as request
+// or trace IDs out of the context given to the *Ctx methods.
+func WithContextExtractor(fn func(ctx context.Context) Fields) Option {
+	return func(o *options) {
+		o.ctxFields = fn
+	}
+}
+
+func (l *Logger) contextFields(ctx context.Context, base Fields) Fields {
+	fromCtx := FieldsFromContext(ctx)
+	var extracted Fields
+	if l.opt.ctxFields != nil {
+		extracted = l.opt.ctxFields(ctx)
+	}
+	err := ctx.Err()
+	if len(fromCtx) == 0 && len(extracted) == 0 && err == nil {
+		return base
+	}
+
+	fields := make(Fields, len(base)+len(fromCtx)+len(extracted)+1)
+	for _, src := range []Fields{base, fromCtx, extracted} {
+		for k, v := range src {
+			fields[k] = v
+		}
+	}
+	if err != nil {
+		fields["ctx_err"] = err.Error()
+	}
+	return fields
+}
+
+func (l *Logger) ctxEntry(ctx context.Context) *Entry {
+	e := l.entry()
+	e.Context = ctx
+	return e
+}
+
+func (l *Logger) DebugCtx(ctx context.Context, args ...any) {
+	if l.enabledCtx(ctx, DebugLevel) {
+		l.ctxEntry(ctx).write(DebugLevel, FmtEmptySeparate, args...)
+	}
+}
+
+func (l *Logger) InfoCtx(ctx context.Context, args ...any) {
+	if l.enabledCtx(ctx, InfoLevel) {
+		l.ctxEntry(ctx).write(InfoLevel, FmtEmptySeparate, args...)
+	}
+}
+
+func (l *Logger) WarnCtx(ctx context.Context, args ...any) {
+	if l.enabledCtx(ctx, WarnLevel) {
+		l.ctxEntry(ctx).write(WarnLevel, FmtEmptySeparate, args...)
+	}
+}
+
+func (l *Logger) ErrorCtx(ctx context.Context, args ...any) {
+	if l.enabledCtx(ctx, ErrorLevel) {
+		l.ctxEntry(ctx).write(ErrorLevel, FmtEmptySeparate, args...)
+	}
+}
+
+func (l *Logger) DebugfCtx(ctx context.Context, format string, args ...any) {
+	if l.enabledCtx(ctx, DebugLevel) {
+		l.ctxEntry(ctx).write(DebugLevel, format, args...)
+	}
+}
+
+func (l *Logger) InfofCtx(ctx context.Context, format string, args ...any) {
+	if l.enabledCtx(ctx, InfoLevel) {
+		l.ctxEntry(ctx).write(InfoLevel, format, args...)
+	}
+}
+
+func (l *Logger) WarnfCtx(ctx context.Context, format string, args ...any) {
+	if l.enabledCtx(ctx, WarnLevel) {
+		l.ctxEntry(ctx).write(WarnLevel, format, args...)
+	}
+}
+
+func (l *Logger) ErrorfCtx(ctx context.Context, format string, args ...any) {
+	if l.enabledCtx(ctx, ErrorLevel) {
+		l.ctxEntry(ctx).write(ErrorLevel, format, args...)
+	}
+}
+
+// std logger
+func DebugCtx(ctx context.Context, args ...any) {
+	if std.enabledCtx(ctx, DebugLevel) {
+		std.ctxEntry(ctx).write(DebugLevel, FmtEmptySeparate, args...)
+	}
+}
+
+func InfoCtx(ctx context.Context, args ...any) {
+	if std.enabledCtx(ctx, InfoLevel) {
+		std.ctxEntry(ctx).write(InfoLevel, FmtEmptySeparate, args...)
+	}
+}
+
+func WarnCtx(ctx context.Context, args ...any) {
+	if std.enabledCtx(ctx, WarnLevel) {
+		std.ctxEntry(ctx).write(WarnLevel, FmtEmptySeparate, args...)
+	}
+}
+
+func ErrorCtx(ctx context.Context, args ...any) {
+	if std.enabledCtx(ctx, ErrorLevel) {
+		std.ctxEntry(ctx).write(ErrorLevel, FmtEmptySeparate, args...)
+	}
+}
+
+func DebugfCtx(ctx context.Context, format string, args ...any) {
+	if std.enabledCtx(ctx, DebugLevel) {
+		std.ctxEntry(ctx).write(DebugLevel, format, args...)
+	}
+}
+
+func InfofCtx(ctx context.Context, format string, args ...any) {
+	if std.enabledCtx(ctx, InfoLevel) {
+		std.ctxEntry(ctx).write(InfoLevel, format, args...)
+	}
+}
+
+func WarnfCtx(ctx context.Context, format string, args ...any) {
+	if std.enabledCtx(ctx, WarnLevel) {
+		std.ctxEntry(ctx).write(WarnLevel, format, args...)
+	}
+}
+
+func ErrorfCtx(ctx context.Context, format string, args ...any) {
+	if std.enabledCtx(ctx, ErrorLevel) {
+		std.ctxEntry(ctx).write(ErrorLevel, format, args...)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"io"
+)
+
+// DecoratedWriter adds a static prefix and suffix to every line written
+// to W, such as the "@cee: " cookie of rsyslog or a container name. The
+// suffix goes before the newline.
+type DecoratedWriter struct {
+	W      io.Writer
+	Prefix string
+	Suffix string
+}
+
+// Decorate wraps w, see DecoratedWriter. OpenSink does the same for the
+// prefix and suffix query parameters of any sink.
+func Decorate(w io.Writer, prefix, suffix string) *DecoratedWriter {
+	return &DecoratedWriter{W: w, Prefix: prefix, Suffix: suffix}
+}
+
+func (d *DecoratedWriter) Write(p []byte) (int, error) {
+	return d.WriteLevel(InfoLevel, p)
+}
+
+// WriteLevel decorates p and hands it to W in a single write, with lvl
+// when W is a LevelWriter.
+func (d *DecoratedWriter) WriteLevel(lvl Level, p []byte) (int, error) {
+	out := make([]byte, 0, len(p)+len(d.Prefix)+len(d.Suffix)+1)
+	for rest := p; len(rest) > 0; {
+		line := rest
+		nl := false
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			line, rest, nl = rest[:i], rest[i+1:], true
+		} else {
+			rest = nil
+		}
+		out = append(out, d.Prefix...)
+		out = append(out, line...)
+		out = append(out, d.Suffix...)
+		if nl {
+			out = append(out, '\n')
+		}
+	}
+	if err := writeFull(d.W, lvl, out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush flushes or syncs W when it can.
+func (d *DecoratedWriter) Flush() error {
+	switch w := d.W.(type) {
+	case interface{ Flush() error }:
+		return w.Flush()
+	case interface{ Sync() error }:
+		return w.Sync()
+	}
+	return nil
+}
+
+func (d *DecoratedWriter) Close() error {
+	if c, ok := d.W.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
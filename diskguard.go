@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type DiskAction uint8
+
+const (
+	// DiskPause drops entries below the guarded level while disk is low.
+	DiskPause DiskAction = iota
+	// DiskSample keeps one in every 100 entries below the guarded level.
+	DiskSample
+	// DiskPrune deletes the oldest rotated files until space is recovered.
+	DiskPrune
+)
+
+const (
+	diskCheckInterval = 5 * time.Second
+	diskSampleRate    = 100
+)
+
+type diskGuard struct {
+	minFree uint64
+	action  DiskAction
+	level   Level
+	checked time.Time
+	isLow   bool
+	count   uint64
+}
+
+// WithDiskGuard watches the free space of the file system holding the
+// file and applies action to entries below level once it drops under
+// minFree bytes.
+func WithDiskGuard(minFree uint64, action DiskAction, level Level) FileOption {
+	return func(w *FileWriter) {
+		w.guard = &diskGuard{minFree: minFree, action: action, level: level}
+	}
+}
+
+// low reports whether free space is below the threshold, statfs is
+// called at most once per diskCheckInterval, w.mu must be held.
+func (g *diskGuard) low(w *FileWriter) bool {
+	now := time.Now()
+	if now.Sub(g.checked) < diskCheckInterval {
+		return g.isLow
+	}
+	g.checked = now
+
+	free, err := diskFree(filepath.Dir(w.path))
+	if err != nil {
+		g.isLow = false
+		return false
+	}
+	g.isLow = free < g.minFree
+	if g.isLow && g.action == DiskPrune {
+		g.isLow = g.prune(w)
+	}
+	return g.isLow
+}
+
+// short reports whether free space is below the threshold without
+// pruning or touching the cached state, for health checks.
+func (g *diskGuard) short(w *FileWriter) bool {
+	free, err := diskFree(filepath.Dir(w.path))
+	return err == nil && free < g.minFree
+}
+
+func (g *diskGuard) allow(w *FileWriter, lvl Level) bool {
+	if !g.low(w) || lvl >= g.level {
+		return true
+	}
+	switch g.action {
+	case DiskSample:
+		g.count++
+		return g.count%diskSampleRate == 1
+	case DiskPause:
+		return false
+	}
+	return true
+}
+
+func (g *diskGuard) prune(w *FileWriter) bool {
+	backups, err := w.backups()
+	if err != nil {
+		return true
+	}
+	for _, b := range backups {
+		if err := os.Remove(b); err != nil {
+			continue
+		}
+		if free, err := diskFree(filepath.Dir(w.path)); err == nil && free >= g.minFree {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,13 @@
+package main
+
+import "context"
+
+// WithDynamicLevel resolves the level of the *Ctx methods through fn on
+// every call, so a feature-flag provider can raise verbosity for given
+// users, tenants or canary instances without a restart. A level set with
+// ContextWithLevel still takes precedence.
+func WithDynamicLevel(fn func(ctx context.Context) Level) Option {
+	return func(o *options) {
+		o.dynamicLevel = fn
+	}
+}
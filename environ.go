@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Environment variables written by Environ and read by NewFromEnviron.
+const (
+	EnvLevel     = "LOGIE_LEVEL"
+	EnvStdLevel  = "LOGIE_STD_LEVEL"
+	EnvFormatter = "LOGIE_FORMATTER"
+	EnvCaller    = "LOGIE_CALLER"
+	EnvOutput    = "LOGIE_OUTPUT"
+	EnvName      = "LOGIE_NAME"
+)
+
+// Environ describes the configuration of l as KEY=value pairs suitable
+// for exec.Cmd.Env, so a spawned helper can log consistently through
+// NewFromEnviron. Only the level, std level, formatter, caller, name and
+// outputs expressible as a sink URL are carried over.
+func (l *Logger) Environ() []string {
+	env := []string{
+		EnvLevel + "=" + LevelMapping[l.opt.level],
+		EnvStdLevel + "=" + LevelMapping[l.opt.stdLevel],
+		EnvCaller + "=" + strconv.FormatBool(!l.opt.disableCaller),
+	}
+	switch l.opt.formatter.(type) {
+	case *TextFormatter:
+		env = append(env, EnvFormatter+"=text")
+	case *JSONFormatter:
+		env = append(env, EnvFormatter+"=json")
+	}
+	if out := sinkURL(l.opt.position); out != "" {
+		env = append(env, EnvOutput+"="+out)
+	}
+	if l.name != "" {
+		env = append(env, EnvName+"="+l.name)
+	}
+	return env
+}
+
+func sinkURL(w interface{}) string {
+	switch w {
+	case os.Stderr:
+		return "stderr:"
+	case os.Stdout:
+		return "stdout:"
+	}
+	path := outputPath(w)
+	if path == "" {
+		return ""
+	}
+	// the child may run in another directory, and the path may need escaping
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	abs = filepath.ToSlash(abs)
+	if !strings.HasPrefix(abs, "/") {
+		abs = "/" + abs
+	}
+	u := url.URL{Scheme: "file", Path: abs}
+	return u.String()
+}
+
+// NewFromEnviron builds a logger from the variables set by Environ, opts
+// are applied last. Unset variables keep the defaults of New.
+func NewFromEnviron(opts ...Option) (*Logger, error) {
+	var envOpts []Option
+	for _, lv := range []struct {
+		key string
+		opt func(Level) Option
+	}{{EnvLevel, WithLevel}, {EnvStdLevel, WithStdLevel}} {
+		v := os.Getenv(lv.key)
+		if v == "" {
+			continue
+		}
+		var lvl Level
+		if err := lvl.UnmarshalText([]byte(v)); err != nil {
+			return nil, fmt.Errorf("logie: %s: %w", lv.key, err)
+		}
+		envOpts = append(envOpts, lv.opt(lvl))
+	}
+	if v := os.Getenv(EnvFormatter); v != "" {
+		f, err := FormatterByName(strings.ToLower(v))
+		if err != nil {
+			return nil, err
+		}
+		envOpts = append(envOpts, WithFormatter(f))
+	}
+	if v := os.Getenv(EnvCaller); v != "" {
+		caller, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("logie: %s: %w", EnvCaller, err)
+		}
+		envOpts = append(envOpts, WithCaller(caller))
+	}
+	if v := os.Getenv(EnvOutput); v != "" {
+		w, err := OpenSink(v)
+		if err != nil {
+			return nil, err
+		}
+		envOpts = append(envOpts, WithPosition(w))
+	}
+
+	l := New(append(envOpts, opts...)...)
+	if name := os.Getenv(EnvName); name != "" {
+		l = l.Named(name)
+	}
+	return l, nil
+}
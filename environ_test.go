@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCallerOption(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func(out *collector) (*Logger, error)
+		want bool
+	}{
+		{
+			name: "with caller",
+			new: func(out *collector) (*Logger, error) {
+				return New(WithPosition(out), WithCaller(true)), nil
+			},
+			want: true,
+		},
+		{
+			name: "without caller",
+			new: func(out *collector) (*Logger, error) {
+				return New(WithPosition(out), WithCaller(false)), nil
+			},
+		},
+		{
+			name: "deprecated inverted option",
+			new: func(out *collector) (*Logger, error) {
+				return New(WithPosition(out), WithEnableCaller(true)), nil
+			},
+		},
+		{
+			name: "development",
+			new: func(out *collector) (*Logger, error) {
+				return NewDevelopment(WithPosition(out)), nil
+			},
+			want: true,
+		},
+		{
+			name: "production",
+			new: func(out *collector) (*Logger, error) {
+				l := NewProduction(WithPosition(out))
+				return l, l.Close()
+			},
+		},
+		{
+			name: "environment",
+			new: func(out *collector) (*Logger, error) {
+				t.Setenv(EnvCaller, "false")
+				return NewFromEnviron(WithPosition(out))
+			},
+		},
+		{
+			name: "environment overridden",
+			new: func(out *collector) (*Logger, error) {
+				t.Setenv(EnvCaller, "false")
+				return NewFromEnviron(WithPosition(out), WithCaller(true))
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &collector{}
+			l, err := tt.new(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := !l.opt.disableCaller; got != tt.want {
+				t.Errorf("caller recorded = %v, want %v", got, tt.want)
+			}
+			if env := strings.Join(l.Environ(), " "); !strings.Contains(env, EnvCaller+"="+strconv.FormatBool(tt.want)) {
+				t.Errorf("Environ() = %s, want the caller setting carried over", env)
+			}
+		})
+	}
+}
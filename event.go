@@ -0,0 +1,35 @@
+package main
+
+import "io"
+
+// WithEventPosition sends entries written by Event to w instead of the
+// diagnostic output.
+func WithEventPosition(w io.Writer) Option {
+	return func(o *options) {
+		o.eventPosition = w
+	}
+}
+
+func Event(name string, fields Fields) {
+	std.eventLogger(name, fields).entry().write(InfoLevel, FmtEmptySeparate, name)
+}
+
+// Event writes an audit record with the event name and its actor, target
+// and outcome fields. Events are not subject to the logger level.
+func (l *Logger) Event(name string, fields Fields) {
+	l.eventLogger(name, fields).entry().write(InfoLevel, FmtEmptySeparate, name)
+}
+
+func (l *Logger) eventLogger(name string, fields Fields) *Logger {
+	record := Fields{"event": name, "actor": "", "target": "", "outcome": "unknown"}
+	for k, v := range fields {
+		record[k] = v
+	}
+
+	return l.WithFields(record).derive(func(o *options) {
+		o.level = TraceLevel
+		if o.eventPosition != nil {
+			o.position = o.eventPosition
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package main
+
+// The Msg and KV methods avoid the variadic slice of the other methods,
+// a disabled level returns before an entry is taken. The caller still
+// boxes the value of a KV call into an interface, which allocates unless
+// it is a constant or a small integer.
+
+func (l *Logger) DebugMsg(msg string) {
+	if l.enabled(DebugLevel) {
+		l.entry().write(DebugLevel, FmtEmptySeparate, msg)
+	}
+}
+
+func (l *Logger) InfoMsg(msg string) {
+	if l.enabled(InfoLevel) {
+		l.entry().write(InfoLevel, FmtEmptySeparate, msg)
+	}
+}
+
+func (l *Logger) WarnMsg(msg string) {
+	if l.enabled(WarnLevel) {
+		l.entry().write(WarnLevel, FmtEmptySeparate, msg)
+	}
+}
+
+func (l *Logger) ErrorMsg(msg string) {
+	if l.enabled(ErrorLevel) {
+		l.entry().write(ErrorLevel, FmtEmptySeparate, msg)
+	}
+}
+
+func (l *Logger) kvEntry(key string, value any) *Entry {
+	e := l.entry()
+	e.extra = Fields{key: value}
+	return e
+}
+
+func (l *Logger) DebugKV(msg, key string, value any) {
+	if l.enabled(DebugLevel) {
+		l.kvEntry(key, value).write(DebugLevel, FmtEmptySeparate, msg)
+	}
+}
+
+func (l *Logger) InfoKV(msg, key string, value any) {
+	if l.enabled(InfoLevel) {
+		l.kvEntry(key, value).write(InfoLevel, FmtEmptySeparate, msg)
+	}
+}
+
+func (l *Logger) WarnKV(msg, key string, value any) {
+	if l.enabled(WarnLevel) {
+		l.kvEntry(key, value).write(WarnLevel, FmtEmptySeparate, msg)
+	}
+}
+
+func (l *Logger) ErrorKV(msg, key string, value any) {
+	if l.enabled(ErrorLevel) {
+		l.kvEntry(key, value).write(ErrorLevel, FmtEmptySeparate, msg)
+	}
+}
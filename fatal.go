@@ -0,0 +1,94 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// fatalStarted is set by the first Fatal call of any logger.
+var fatalStarted int32
+
+// WithFatalHook registers fn to run before Fatal exits the process, after
+// the outputs are flushed. Hooks must not call Fatal.
+func WithFatalHook(fn func()) Option {
+	return func(o *options) {
+		o.fatalHooks = append(o.fatalHooks, fn)
+	}
+}
+
+// WithFatalExitCode sets the exit code of Fatal and Fatalf, 1 by default.
+func WithFatalExitCode(code int) Option {
+	return func(o *options) {
+		o.fatalCode = code
+	}
+}
+
+// FatalCode logs like Fatal and exits with code, for supervisors telling
+// failure categories apart.
+func (l *Logger) FatalCode(code int, args ...any) {
+	if l.enabled(FatalLevel) {
+		l.entry().write(FatalLevel, FmtEmptySeparate, args...)
+	}
+	l.exit(code)
+}
+
+func FatalCode(code int, args ...any) {
+	if std.enabled(FatalLevel) {
+		std.entry().write(FatalLevel, FmtEmptySeparate, args...)
+	}
+	std.exit(code)
+}
+
+// exit ends the process for the first Fatal call: it waits for in-flight
+// writes, drains the async queue, flushes the outputs and runs the fatal
+// hooks, once. Concurrent Fatal calls block until the process exits, so
+// the exit code is the one of the first call.
+func (l *Logger) exit(code int) {
+	if !atomic.CompareAndSwapInt32(&fatalStarted, 0, 1) {
+		select {}
+	}
+	if err := l.Close(); err != nil {
+		l.reportError(err)
+	}
+	l.mu.Lock()
+	l.flushOutputs()
+	l.mu.Unlock()
+	for _, fn := range l.opt.fatalHooks {
+		l.callFatalHook(fn)
+	}
+	os.Exit(code)
+}
+
+func (l *Logger) flushOutputs() {
+	seen := make(map[io.Writer]bool)
+	outputs := []io.Writer{l.opt.position, l.destination(FatalLevel)}
+	for _, r := range l.opt.routes {
+		outputs = append(outputs, r.Sink)
+	}
+	for _, w := range outputs {
+		if w == nil || seen[w] {
+			continue
+		}
+		seen[w] = true
+		var err error
+		switch f := w.(type) {
+		case interface{ Flush() error }:
+			err = f.Flush()
+		case interface{ Sync() error }:
+			err = f.Sync()
+		}
+		if err != nil {
+			l.reportError(err)
+		}
+	}
+}
+
+func (l *Logger) callFatalHook(fn func()) {
+	defer func() {
+		if v := recover(); v != nil {
+			l.reportError(newPanicError("fatal hook", v))
+		}
+	}()
+	fn()
+}
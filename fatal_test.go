@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// fatalCases run in a child process selected by LOGIE_FATAL_CASE, they
+// log to stdout.
+var fatalCases = map[string]func(){
+	"default code": func() {
+		New(WithPosition(os.Stdout), WithFormatter(&TextFormatter{IgnoreBasicFields: true})).Fatal("bye")
+	},
+	"exit code": func() {
+		New(WithPosition(os.Stdout), WithFormatter(&TextFormatter{IgnoreBasicFields: true}),
+			WithFatalExitCode(3)).Fatal("bye")
+	},
+	"drains async queue and runs hooks": func() {
+		l := New(WithPosition(os.Stdout), WithFormatter(&TextFormatter{IgnoreBasicFields: true}), WithAsync(16),
+			WithFatalHook(func() { fmt.Println("hook") }),
+			WithFatalHook(func() { panic("broken hook") }),
+			WithFatalHook(func() { fmt.Println("after panic") }))
+		l.Info("queued")
+		l.FatalCode(4, "bye")
+	},
+	"first call wins": func() {
+		var l *Logger
+		l = New(WithPosition(os.Stdout), WithFormatter(&TextFormatter{IgnoreBasicFields: true}),
+			WithFatalHook(func() {
+				go l.FatalCode(6, "second")
+				time.Sleep(50 * time.Millisecond)
+				fmt.Println("hook")
+			}))
+		l.FatalCode(5, "first")
+	},
+}
+
+func TestFatal(t *testing.T) {
+	if name := os.Getenv("LOGIE_FATAL_CASE"); name != "" {
+		fatalCases[name]()
+		return
+	}
+	tests := []struct {
+		name string
+		code int
+		want string
+	}{
+		{name: "default code", code: 1, want: "bye\n"},
+		{name: "exit code", code: 3, want: "bye\n"},
+		// the Fatal entry goes through the priority queue, ahead of Info
+		{name: "drains async queue and runs hooks", code: 4, want: "bye\nqueued\nhook\nafter panic\n"},
+		{name: "first call wins", code: 5, want: "first\nsecond\nhook\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(os.Args[0], "-test.run=^TestFatal$")
+			cmd.Env = append(os.Environ(), "LOGIE_FATAL_CASE="+tt.name)
+			out, err := cmd.Output()
+			var exit *exec.ExitError
+			if !errors.As(err, &exit) || exit.ExitCode() != tt.code {
+				t.Fatalf("child exited with %v, want code %d", err, tt.code)
+			}
+			if got := string(out); got != tt.want {
+				t.Errorf("output %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import "sort"
+
+// Fields are key/value pairs attached to every entry of a logger.
+type Fields map[string]any
+
+func WithFields(fields Fields) *Logger {
+	return std.WithFields(fields)
+}
+
+// WithFields returns a child logger adding fields to each entry, the
+// child shares options and output with l.
+func (l *Logger) WithFields(fields Fields) *Logger {
+	child := l.clone()
+	child.fields = mergeFields(l.fields, fields)
+	return child
+}
+
+// mergeFields returns a new map holding a and b, b winning on duplicates.
+func mergeFields(a, b Fields) Fields {
+	merged := make(Fields, len(a)+len(b))
+	for k, v := range a {
+		merged[k] = v
+	}
+	for k, v := range b {
+		merged[k] = v
+	}
+	return merged
+}
+
+func (f Fields) keys() []string {
+	if len(f) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FieldType is the expected type of a field in a FieldSchema.
+type FieldType uint8
+
+const (
+	AnyType FieldType = iota
+	StringType
+	// IntType accepts every signed and unsigned integer type.
+	IntType
+	FloatType
+	BoolType
+	DurationType
+	TimeType
+)
+
+var fieldTypeNames = map[FieldType]string{
+	AnyType:      "any",
+	StringType:   "string",
+	IntType:      "int",
+	FloatType:    "float",
+	BoolType:     "bool",
+	DurationType: "duration",
+	TimeType:     "time",
+}
+
+func (t FieldType) String() string {
+	return fieldTypeNames[t]
+}
+
+// FieldSchema declares the fields every entry of the logger named Logger
+// and of its children must carry, e.g. order_id for "payment".
+type FieldSchema struct {
+	Logger   string
+	Required []string
+	Types    map[string]FieldType
+}
+
+// WithFieldSchemas validates entries against the schema of their logger,
+// an entry missing a required key or holding a field of another type
+// logs a warning pointing at the call site. Meant for development, the
+// entry itself is written unchanged.
+func WithFieldSchemas(schemas ...FieldSchema) Option {
+	return func(o *options) {
+		o.schemas = append(o.schemas, schemas...)
+	}
+}
+
+func (s *FieldSchema) matches(name string) bool {
+	return name == s.Logger || strings.HasPrefix(name, s.Logger+".")
+}
+
+// checkSchema is called from Entry.write, skip locates the log call.
+func (e *Entry) checkSchema(skip int) {
+	for i := range e.logger.opt.schemas {
+		s := &e.logger.opt.schemas[i]
+		if !s.matches(e.logger.name) {
+			continue
+		}
+		var missing, mistyped []string
+		for _, k := range s.Required {
+			if _, ok := e.Fields[k]; !ok {
+				missing = append(missing, k)
+			}
+		}
+		for k, t := range s.Types {
+			if v, ok := e.Fields[k]; ok && !t.accepts(v) {
+				mistyped = append(mistyped, fmt.Sprintf("%s: want %s, got %T", k, t, v))
+			}
+		}
+		if len(missing) == 0 && len(mistyped) == 0 {
+			continue
+		}
+		sort.Strings(mistyped)
+		fields := Fields{"schema": s.Logger}
+		if len(missing) > 0 {
+			fields["missing"] = strings.Join(missing, ",")
+		}
+		if len(mistyped) > 0 {
+			fields["mistyped"] = strings.Join(mistyped, "; ")
+		}
+		e.logger.misuse(skip+1, "logie: entry does not match field schema", fields)
+	}
+}
+
+func (t FieldType) accepts(v any) bool {
+	switch t {
+	case DurationType:
+		_, ok := v.(time.Duration)
+		return ok
+	case TimeType:
+		_, ok := v.(time.Time)
+		return ok
+	case AnyType:
+		return true
+	}
+	if v == nil {
+		return false
+	}
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.String:
+		return t == StringType
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return t == IntType
+	case reflect.Float32, reflect.Float64:
+		return t == FloatType
+	case reflect.Bool:
+		return t == BoolType
+	}
+	return false
+}
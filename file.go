@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var errFileClosed = errors.New("logie: file writer closed")
+
+const backupTimeFormat = "20060102T150405.000"
+
+type FileOption func(*FileWriter)
+
+// FileWriter appends entries to a file, optionally rotating it once it
+// grows beyond a size limit. Rotated files are kept next to the active one
+// as path.<timestamp>.
+type FileWriter struct {
+	mu      sync.Mutex
+	path    string
+	active  string
+	mode    rotateMode
+	fd      *os.File
+	size    int64
+	maxSize int64
+	guard   *diskGuard
+}
+
+func NewFileWriter(path string, opts ...FileOption) (*FileWriter, error) {
+	w := &FileWriter{path: path}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func WithMaxSize(size int64) FileOption {
+	return func(w *FileWriter) {
+		w.maxSize = size
+	}
+}
+
+func (w *FileWriter) Path() string {
+	return w.path
+}
+
+func (w *FileWriter) open() error {
+	w.active = w.path
+	if w.mode == rotateSymlink {
+		w.active = w.path + "." + time.Now().Format(backupTimeFormat)
+	}
+	fd, err := os.OpenFile(w.active, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := fd.Stat()
+	if err != nil {
+		_ = fd.Close()
+		return err
+	}
+	w.fd, w.size = fd, fi.Size()
+	if w.mode == rotateSymlink {
+		return relink(filepath.Base(w.active), w.path)
+	}
+	return nil
+}
+
+// Write is WriteLevel at InfoLevel, the disk guard applies to writes
+// that do not come from a logger too.
+func (w *FileWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(InfoLevel, p)
+}
+
+func (w *FileWriter) WriteLevel(lvl Level, p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.guard != nil && !w.guard.allow(w, lvl) {
+		return len(p), nil
+	}
+	return w.write(p)
+}
+
+func (w *FileWriter) write(p []byte) (int, error) {
+	if w.fd == nil {
+		return 0, errFileClosed
+	}
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.fd.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *FileWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rotate()
+}
+
+func (w *FileWriter) rotate() error {
+	if w.mode == rotateCopyTruncate && w.fd != nil {
+		return w.copyTruncate()
+	}
+	if w.fd != nil {
+		if err := w.fd.Close(); err != nil {
+			return err
+		}
+		w.fd = nil
+	}
+	if w.mode == rotateSymlink {
+		return w.open()
+	}
+	backup := w.path + "." + time.Now().Format(backupTimeFormat)
+	if err := os.Rename(w.path, backup); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return w.open()
+}
+
+// Backups returns the rotated files of w, oldest first. The active file is
+// never part of them.
+func (w *FileWriter) Backups() ([]string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.backups()
+}
+
+func (w *FileWriter) backups() ([]string, error) {
+	matches, err := filepath.Glob(w.path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	backups := matches[:0]
+	for _, m := range matches {
+		if m == w.active {
+			continue
+		}
+		if _, err := time.Parse(backupTimeFormat, m[len(w.path)+1:]); err == nil {
+			backups = append(backups, m)
+		}
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+func (w *FileWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.fd == nil {
+		return errFileClosed
+	}
+	return w.fd.Sync()
+}
+
+func (w *FileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.fd == nil {
+		return nil
+	}
+	err := w.fd.Close()
+	w.fd = nil
+	return err
+}
+
+func (w *FileWriter) Healthy() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.fd == nil {
+		return errFileClosed
+	}
+	if err := probeFile(w.fd); err != nil {
+		return err
+	}
+	if w.guard != nil && w.guard.short(w) {
+		return fmt.Errorf("logie: free disk space for %s below %d bytes", w.path, w.guard.minFree)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// noSpace is more free space than any file system has, a guard needing it
+// always finds the disk low.
+const noSpace = 1 << 62
+
+func TestFileWriterRotates(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []FileOption
+		wantBackups int
+	}{
+		{"no limit", nil, 0},
+		{"rename", []FileOption{WithMaxSize(4)}, 1},
+		{"copy truncate", []FileOption{WithMaxSize(4), WithCopyTruncate()}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			w, err := NewFileWriter(path, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+			for _, e := range []string{"aaa\n", "bbb\n"} {
+				if _, err := w.Write([]byte(e)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			backups, err := w.Backups()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(backups) != tt.wantBackups {
+				t.Errorf("backups = %q, want %d", backups, tt.wantBackups)
+			}
+		})
+	}
+}
+
+func TestFileWriterGuardsPlainWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewFileWriter(path, WithDiskGuard(noSpace, DiskPause, ErrorLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if n, err := w.Write([]byte("info\n")); n != 5 || err != nil {
+		t.Fatalf("Write() = %d, %v", n, err)
+	}
+	if _, err := w.WriteLevel(ErrorLevel, []byte("error\n")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "error\n" {
+		t.Errorf("file = %q, want only the entry at the guarded level", data)
+	}
+}
+
+func TestFileWriterHealthyKeepsBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewFileWriter(path, WithDiskGuard(noSpace, DiskPrune, ErrorLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Healthy() == nil {
+		t.Error("Healthy() = nil with the disk below the guard")
+	}
+	if backups, _ := w.Backups(); len(backups) != 1 {
+		t.Errorf("backups = %q after Healthy, want the rotated file kept", backups)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+)
+
+// variableParts matches the parts of a message that differ between
+// occurrences of the same error: hex values, UUIDs and numbers.
+var variableParts = regexp.MustCompile(`0x[0-9a-fA-F]+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|\d+`)
+
+// WithFingerprint adds error.fingerprint to Error and above entries, a
+// stable hash of the error type, its message without variable parts and
+// the calling function, so identical errors group across hosts.
+func WithFingerprint() Option {
+	return func(o *options) {
+		o.fingerprint = true
+	}
+}
+
+func (e *Entry) addFingerprint() {
+	if !e.logger.opt.fingerprint || e.Level < ErrorLevel {
+		return
+	}
+
+	kind, msg := "message", ""
+	if err := entryError(e); err != nil {
+		kind, msg = fmt.Sprintf("%T", err), errorText(err)
+	} else if e.Format != FmtEmptySeparate {
+		msg = e.Format
+	} else {
+		msg = fmt.Sprint(e.Args...)
+	}
+
+	fn := e.Func
+	if fn == "" {
+		fn = e.caller
+	}
+	h := sha256.New()
+	// the line is left out so the fingerprint survives unrelated edits
+	fmt.Fprintf(h, "%s\x00%s\x00%s", kind, variableParts.ReplaceAllString(msg, "#"), fn)
+	e.Fields = mergeFields(e.Fields, Fields{"error.fingerprint": hex.EncodeToString(h.Sum(nil)[:8])})
+}
+
+// errorText returns the message of err, a panicking Error method yields
+// the same placeholder the formatters write.
+func errorText(err error) (msg string) {
+	defer func() {
+		if r := recover(); r != nil {
+			msg = panicPlaceholder(err, r)
+		}
+	}()
+	return err.Error()
+}
+
+// entryError returns the error logged by e, from its arguments or its
+// error field.
+func entryError(e *Entry) error {
+	for _, arg := range e.Args {
+		if err, ok := arg.(error); ok && err != nil {
+			return err
+		}
+	}
+	if err, ok := e.Fields["error"].(error); ok {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type panickingError struct{}
+
+func (panickingError) Error() string { panic("no message") }
+
+func fingerprint(t *testing.T, log func(l *Logger), opts ...Option) string {
+	t.Helper()
+	var buf bytes.Buffer
+	l := New(append([]Option{WithPosition(&buf), WithFormatter(&JSONFormatter{}), WithFingerprint()}, opts...)...)
+	log(l)
+	var m map[string]any
+	if err := decodeJSON(buf.Bytes(), &m); err != nil {
+		t.Fatalf("decode %s: %v", buf.Bytes(), err)
+	}
+	fp, _ := m["error.fingerprint"].(string)
+	return fp
+}
+
+func logOrderFailed(l *Logger, id int) { l.Errorf("order %d failed", id) }
+
+func logPaymentFailed(l *Logger, id int) { l.Errorf("order %d failed", id) }
+
+func TestFingerprint(t *testing.T) {
+	catalog := func(text string) MessageCatalog {
+		return func(id string) (string, bool) { return text, id == "order %d failed" }
+	}
+	tests := []struct {
+		name      string
+		a, b      func(l *Logger)
+		aOpts     []Option
+		bOpts     []Option
+		wantEqual bool
+	}{
+		{
+			name:      "variable parts",
+			a:         func(l *Logger) { logOrderFailed(l, 1) },
+			b:         func(l *Logger) { logOrderFailed(l, 2) },
+			wantEqual: true,
+		},
+		{
+			name:      "locales",
+			a:         func(l *Logger) { logOrderFailed(l, 1) },
+			b:         func(l *Logger) { logOrderFailed(l, 1) },
+			aOpts:     []Option{WithMessageCatalog(catalog("order %d failed"))},
+			bOpts:     []Option{WithMessageCatalog(catalog("Bestellung %d fehlgeschlagen"))},
+			wantEqual: true,
+		},
+		{
+			name: "calling functions",
+			a:    func(l *Logger) { logOrderFailed(l, 1) },
+			b:    func(l *Logger) { logPaymentFailed(l, 1) },
+		},
+		{
+			name:  "calling functions without caller capture",
+			a:     func(l *Logger) { logOrderFailed(l, 1) },
+			b:     func(l *Logger) { logPaymentFailed(l, 1) },
+			aOpts: []Option{WithCaller(false)},
+			bOpts: []Option{WithCaller(false)},
+		},
+		{
+			name: "error types",
+			a:    func(l *Logger) { l.Error(errors.New("timeout")) },
+			b:    func(l *Logger) { l.Error(fmt.Errorf("timeout")) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := fingerprint(t, tt.a, tt.aOpts...), fingerprint(t, tt.b, tt.bOpts...)
+			if a == "" || b == "" {
+				t.Fatalf("missing fingerprint: %q %q", a, b)
+			}
+			if (a == b) != tt.wantEqual {
+				t.Errorf("fingerprints %s and %s, want equal %v", a, b, tt.wantEqual)
+			}
+		})
+	}
+}
+
+func TestFingerprintPanickingError(t *testing.T) {
+	if fp := fingerprint(t, func(l *Logger) { l.Error(panickingError{}) }); fp == "" {
+		t.Error("no fingerprint for an error whose Error method panics")
+	}
+}
+
+func TestFingerprintBelowError(t *testing.T) {
+	if fp := fingerprint(t, func(l *Logger) { l.Warn("slow") }); fp != "" {
+		t.Errorf("fingerprint %s on a warning", fp)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+var printfFuncs = []string{
+	"Debugf", "Infof", "Warnf", "Errorf", "Panicf", "Fatalf",
+	"DebugfCtx", "InfofCtx", "WarnfCtx", "ErrorfCtx",
+}
+
+// PrintfFuncs returns the printf-like functions of the package in the
+// form expected by go vet -printf.funcs.
+func PrintfFuncs() string {
+	return strings.Join(printfFuncs, ",")
+}
+
+// WithFormatCheck validates format verbs against their arguments before
+// formatting, a mismatch logs a warning pointing at the call site and the
+// entry is written with its raw format and arguments instead of %!verb
+// noise. Format verbs passed to non-f methods and odd key-value lists
+// given to the w methods are reported too. Meant for development, it
+// costs a parse of every format.
+func WithFormatCheck() Option {
+	return func(o *options) {
+		o.formatCheck = true
+	}
+}
+
+// checkFormat is called from Entry.write, skip locates the log call.
+func (e *Entry) checkFormat(skip int) {
+	problem := formatProblem(e.Format, e.Args)
+	if problem == "" {
+		return
+	}
+
+	e.logger.misuse(skip+1, "logie: format string mismatch", Fields{"format": e.Format, "problem": problem})
+	e.Args = []any{e.Format + " " + fmt.Sprint(e.Args)}
+	e.Format = FmtEmptySeparate
+}
+
+// checkPrint warns about a format string passed to a non-f method, as in
+// Info("count: %d", n), the entry itself is left untouched.
+func (e *Entry) checkPrint(skip int) {
+	if len(e.Args) < 2 {
+		return
+	}
+	format, ok := e.Args[0].(string)
+	if !ok || !hasVerb(format) {
+		return
+	}
+	e.logger.misuse(skip+1, "logie: format verb in a non-f call", Fields{
+		"format":  format,
+		"problem": "use the f variant of the method to format arguments",
+	})
+}
+
+// misuse logs a development diagnostic for the log call skip frames up.
+func (l *Logger) misuse(skip int, msg string, fields Fields) {
+	if _, file, line, ok := runtime.Caller(skip + 1); ok {
+		fields["call_site"] = fmt.Sprintf("%s:%d", file, line)
+	}
+	l.diagnose(msg, fields)
+}
+
+func (l *Logger) diagnose(msg string, fields Fields) {
+	l.derive(func(o *options) {
+		o.level, o.formatCheck, o.disableCaller = TraceLevel, false, true
+		o.schemas = nil
+	}).WithFields(fields).entry().write(WarnLevel, FmtEmptySeparate, msg)
+}
+
+// hasVerb reports whether s contains a formatting verb other than %%.
+func hasVerb(s string) bool {
+	for i := 0; i < len(s)-1; i++ {
+		if s[i] != '%' {
+			continue
+		}
+		j := i + 1
+		for j < len(s) && strings.IndexByte("+-# 0.123456789", s[j]) >= 0 {
+			j++
+		}
+		if j < len(s) && strings.IndexByte("vTtbcdoOqxXUeEfFgGspw", s[j]) >= 0 {
+			return true
+		}
+		i = j
+	}
+	return false
+}
+
+// formatProblem describes the first mismatch between the verbs of format
+// and args, or returns "" when they agree.
+func formatProblem(format string, args []any) string {
+	argNum := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0", format[i]) >= 0 {
+			i++
+		}
+		for i < len(format) && (format[i] == '*' || format[i] == '.' || (format[i] >= '0' && format[i] <= '9')) {
+			if format[i] == '*' {
+				if argNum >= len(args) {
+					return "missing argument for *"
+				}
+				if _, ok := args[argNum].(int); !ok {
+					return fmt.Sprintf("argument %d for * is %T, not int", argNum+1, args[argNum])
+				}
+				argNum++
+			}
+			i++
+		}
+		if i >= len(format) {
+			return "format ends with an incomplete verb"
+		}
+		if format[i] == '[' {
+			// explicit argument indexes are left to fmt
+			return ""
+		}
+		verb, size := utf8.DecodeRuneInString(format[i:])
+		i += size - 1
+		if verb == '%' {
+			continue
+		}
+		if argNum >= len(args) {
+			return fmt.Sprintf("missing argument for %%%c", verb)
+		}
+		if verb == 'w' {
+			return fmt.Sprintf("%%w has argument %d, entries are formatted like fmt.Sprintf which does not wrap, use %%v", argNum+1)
+		}
+		if !verbAccepts(verb, args[argNum]) {
+			return fmt.Sprintf("%%%c has argument %d of type %T", verb, argNum+1, args[argNum])
+		}
+		argNum++
+	}
+	if argNum < len(args) {
+		return fmt.Sprintf("%d extra arguments", len(args)-argNum)
+	}
+	return ""
+}
+
+func verbAccepts(verb rune, arg any) bool {
+	switch verb {
+	case 'v', 'T', 'p':
+		return true
+	}
+	switch arg.(type) {
+	case fmt.Formatter:
+		return true
+	case nil:
+		return false
+	}
+
+	switch arg.(type) {
+	case bool:
+		return verb == 't'
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr:
+		return strings.ContainsRune("bcdoOqxXU", verb)
+	case float32, float64, complex64, complex128:
+		return strings.ContainsRune("beEfFgGxX", verb)
+	case string, []byte:
+		return strings.ContainsRune("sqxX", verb)
+	case error, fmt.Stringer:
+		return strings.ContainsRune("sqxX", verb)
+	}
+	// composite values are formatted element-wise by fmt, do not guess
+	return true
+}
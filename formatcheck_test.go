@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFormatProblem(t *testing.T) {
+	err := errors.New("boom")
+	tests := []struct {
+		name   string
+		format string
+		args   []any
+		want   string
+	}{
+		{"matching", "%s took %d ms", []any{"load", 12}, ""},
+		{"wrapping verb", "load failed: %w", []any{err}, "%w has argument 1"},
+		{"wrapping verb on a string", "load failed: %w", []any{"boom"}, "%w has argument 1"},
+		{"verb for another type", "took %d ms", []any{"12"}, "%d has argument 1 of type string"},
+		{"missing argument", "%s and %s", []any{"a"}, "missing argument for %s"},
+		{"extra arguments", "%s", []any{"a", "b"}, "1 extra arguments"},
+		{"star width", "%*d", []any{"3", 1}, "argument 1 for * is string"},
+		{"literal percent", "100%% done", nil, ""},
+		{"error as string", "load failed: %v", []any{err}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatProblem(tt.format, tt.args)
+			if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+				t.Errorf("formatProblem(%q) = %q, want %q", tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithFormatCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(l *Logger)
+		warn string
+	}{
+		{"wrapping verb", func(l *Logger) { l.Errorf("load failed: %w", errors.New("boom")) }, "format string mismatch"},
+		{"verb in a non-f call", func(l *Logger) { l.Error("load failed: %w", errors.New("boom")) }, "format verb in a non-f call"},
+		{"valid format", func(l *Logger) { l.Errorf("load failed: %v", errors.New("boom")) }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := New(WithPosition(&buf), WithFormatter(&TextFormatter{IgnoreBasicFields: true}), WithFormatCheck())
+			tt.log(l)
+			out := buf.String()
+			if strings.Contains(out, "%!w") {
+				t.Errorf("output %q holds fmt noise", out)
+			}
+			if got := strings.Contains(out, "logie:"); got != (tt.warn != "") || !strings.Contains(out, tt.warn) {
+				t.Errorf("output %q, want warning %q", out, tt.warn)
+			}
+		})
+	}
+}
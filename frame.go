@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var errFrameTooLarge = errors.New("logie: frame exceeds size limit")
+
+// maxFrameSize bounds the length accepted by FrameReader.
+const maxFrameSize = 64 << 20
+
+// FramedWriter prefixes every write to W with its length as an unsigned
+// varint, so binary payloads can be split reliably when read back with
+// FrameReader. It frames the bytes actually written, so it belongs last,
+// directly around the destination.
+type FramedWriter struct {
+	W io.Writer
+}
+
+// Frame wraps w, see FramedWriter.
+func Frame(w io.Writer) *FramedWriter {
+	return &FramedWriter{W: w}
+}
+
+func (f *FramedWriter) Write(p []byte) (int, error) {
+	return f.WriteLevel(InfoLevel, p)
+}
+
+// WriteLevel hands the frame of p to W in a single write, with lvl when W
+// is a LevelWriter.
+func (f *FramedWriter) WriteLevel(lvl Level, p []byte) (int, error) {
+	out := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(p))
+	out = append(out[:binary.PutUvarint(out, uint64(len(p)))], p...)
+	if err := writeFull(f.W, lvl, out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+type FrameReader struct {
+	r *bufio.Reader
+}
+
+func NewFrameReader(r io.Reader) *FrameReader {
+	return &FrameReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next frame payload, io.EOF after the last one and
+// io.ErrUnexpectedEOF for a truncated frame.
+func (fr *FrameReader) Next() ([]byte, error) {
+	size, err := binary.ReadUvarint(fr.r)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxFrameSize {
+		return nil, errFrameTooLarge
+	}
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(fr.r, frame); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return frame, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	payloads := [][]byte{[]byte("plain\n"), {}, []byte("multi\nline\n"), {0x00, 0xff, '\n', 0x80}, bytes.Repeat([]byte("x"), 300)}
+	var buf bytes.Buffer
+	out := &levelRecorder{}
+	fw := Frame(io.MultiWriter(&buf, out))
+	for _, p := range payloads {
+		if n, err := fw.WriteLevel(WarnLevel, p); err != nil || n != len(p) {
+			t.Fatalf("WriteLevel(%q) = %d, %v", p, n, err)
+		}
+	}
+	fr := NewFrameReader(&buf)
+	for _, want := range payloads {
+		got, err := fr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Next() = %q, want %q", got, want)
+		}
+	}
+	if _, err := fr.Next(); err != io.EOF {
+		t.Errorf("Next() after the last frame = %v, want io.EOF", err)
+	}
+}
+
+func TestFrameWriteLevel(t *testing.T) {
+	out := &levelRecorder{}
+	New(WithPosition(Frame(out)), WithFormatter(&TextFormatter{IgnoreBasicFields: true})).Error("e")
+	if len(out.levels) != 1 || out.levels[0] != ErrorLevel {
+		t.Errorf("levels %v, want [Error] passed through the frame", out.levels)
+	}
+}
+
+func TestFrameReaderErrors(t *testing.T) {
+	huge := make([]byte, binary.MaxVarintLen64)
+	huge = huge[:binary.PutUvarint(huge, maxFrameSize+1)]
+	tests := []struct {
+		name string
+		in   []byte
+		want error
+	}{
+		{name: "empty", in: nil, want: io.EOF},
+		{name: "truncated payload", in: []byte{5, 'a', 'b'}, want: io.ErrUnexpectedEOF},
+		{name: "truncated length", in: []byte{0x80}, want: io.ErrUnexpectedEOF},
+		{name: "too large", in: huge, want: errFrameTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFrameReader(bytes.NewReader(tt.in)).Next(); !errors.Is(err, tt.want) {
+				t.Errorf("Next() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckpointReaderFramed(t *testing.T) {
+	dir := t.TempDir()
+	path, ckpt := filepath.Join(dir, "app.log"), filepath.Join(dir, "app.ckpt")
+	var buf bytes.Buffer
+	fw := Frame(&buf)
+	fw.Write([]byte("one\n"))
+	fw.Write([]byte("two\nlines"))
+	// a frame still being written
+	full := buf.Bytes()
+	fw.Write([]byte("three"))
+	os.WriteFile(path, buf.Bytes()[:len(full)+3], 0o644)
+
+	cr, err := NewCheckpointReader(path, ckpt, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cr.Close()
+	if got := readAll(t, cr, 10); len(got) != 2 || got[1] != "two\nlines" {
+		t.Fatalf("read %q, want the two complete frames", got)
+	}
+	os.WriteFile(path, buf.Bytes(), 0o644)
+	if got := readAll(t, cr, 10); len(got) != 1 || got[0] != "three" {
+		t.Errorf("read %q once the frame was complete, want [three]", got)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// GoldenUpdateEnv rewrites golden files instead of comparing them when
+// set to a non-empty value.
+const GoldenUpdateEnv = "LOGIE_UPDATE_GOLDEN"
+
+var goldenTime = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// NewEntry builds an entry outside of a logger, for formatter tests and
+// previews.
+func NewEntry(lvl Level, msg string, fields Fields) *Entry {
+	e := entry(std)
+	e.Level, e.Time, e.Args, e.Fields = lvl, time.Now(), []any{msg}, fields
+	return e
+}
+
+// Golden formats entries with f and compares the output with the file at
+// path. Timestamps and callers are normalized first so the output is
+// deterministic, set GoldenUpdateEnv to record a new golden file.
+func Golden(t TB, f Formatter, entries []*Entry, path string) {
+	t.Helper()
+
+	var out bytes.Buffer
+	for i, src := range entries {
+		e := entry(std)
+		*e = *src
+		e.Buf, e.Map = new(bytes.Buffer), make(map[string]any, 5)
+		e.Time = goldenTime.Add(time.Duration(i) * time.Second)
+		if e.File != "" {
+			e.File, e.Line, e.Func = "golden.go", 1, "golden.Func"
+		}
+		if err := f.Format(e); err != nil {
+			t.Fatalf("format entry %d: %v", i, err)
+		}
+		out.Write(e.Buf.Bytes())
+	}
+
+	if os.Getenv(GoldenUpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (set %s=1 to create it): %v", GoldenUpdateEnv, err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("output differs from %s:\n--- got\n%s\n--- want\n%s", path, out.Bytes(), want)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+var errNilPosition = errors.New("logie: no output configured")
+
+// HealthChecker is implemented by outputs that can report whether they
+// are still able to accept log entries.
+type HealthChecker interface {
+	Healthy() error
+}
+
+func Healthy() error {
+	return std.Healthy()
+}
+
+// Healthy probes the configured output and reports whether entries can
+// still be written, suitable for readiness checks.
+func (l *Logger) Healthy() error {
+	l.mu.Lock()
+	pos := l.opt.position
+	l.mu.Unlock()
+	return probe(pos)
+}
+
+func probe(w io.Writer) error {
+	switch out := w.(type) {
+	case nil:
+		return errNilPosition
+	case HealthChecker:
+		return out.Healthy()
+	case *os.File:
+		return probeFile(out)
+	case net.Conn:
+		return probeConn(out)
+	}
+	return nil
+}
+
+func probeFile(f *os.File) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("logie: stat %s: %w", f.Name(), err)
+	}
+	if fi.Mode().IsRegular() && fi.Mode().Perm()&0222 == 0 {
+		return fmt.Errorf("logie: %s is not writable", f.Name())
+	}
+	return nil
+}
+
+// probeConn writes zero bytes with a deadline, which fails once the
+// connection is closed or reset without consuming what the peer sent.
+func probeConn(c net.Conn) error {
+	if err := c.SetWriteDeadline(time.Now().Add(time.Second)); err != nil {
+		return fmt.Errorf("logie: probe %s: %w", c.RemoteAddr(), err)
+	}
+	defer c.SetWriteDeadline(time.Time{})
+
+	if _, err := c.Write(nil); err != nil {
+		return fmt.Errorf("logie: connection to %s lost: %w", c.RemoteAddr(), err)
+	}
+	return nil
+}
+
+var errNotConnected = errors.New("logie: not connected")
+
+// probeSinkConn probes the connection of a sink reconnecting on the next
+// entry, the caller holds the lock guarding conn.
+func probeSinkConn(conn net.Conn) error {
+	if conn == nil {
+		return errNotConnected
+	}
+	return probeConn(conn)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSinksAreHealthCheckers(t *testing.T) {
+	sinks := []struct {
+		name string
+		sink any
+	}{
+		{"HTTPSink", (*HTTPSink)(nil)},
+		{"SplunkSink", (*SplunkSink)(nil)},
+		{"AzureMonitorSink", (*AzureMonitorSink)(nil)},
+		{"NATSSink", (*NATSSink)(nil)},
+		{"RedisStreamSink", (*RedisStreamSink)(nil)},
+		{"MQTTSink", (*MQTTSink)(nil)},
+		{"S3Sink", (*S3Sink)(nil)},
+		{"FileWriter", (*FileWriter)(nil)},
+		{"DiskQueue", (*DiskQueue)(nil)},
+	}
+	for _, tt := range sinks {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := tt.sink.(HealthChecker); !ok {
+				t.Errorf("%s does not implement HealthChecker", tt.name)
+			}
+		})
+	}
+}
+
+// connPair returns both ends of a TCP connection.
+func connPair(t *testing.T) (client, server *net.TCPConn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, _ := ln.Accept()
+		accepted <- c
+	}()
+	c, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := <-accepted
+	t.Cleanup(func() {
+		c.Close()
+		s.Close()
+	})
+	return c.(*net.TCPConn), s.(*net.TCPConn)
+}
+
+func TestProbeConnKeepsPendingData(t *testing.T) {
+	client, server := connPair(t)
+	server.Write([]byte("x"))
+	time.Sleep(10 * time.Millisecond)
+	if err := probeConn(client); err != nil {
+		t.Fatalf("probeConn() = %v on a live connection", err)
+	}
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	var b [1]byte
+	if n, err := client.Read(b[:]); n != 1 || b[0] != 'x' {
+		t.Errorf("Read() after the probe = %q, %v, want the byte sent by the peer", b[:n], err)
+	}
+}
+
+func TestProbeConnLost(t *testing.T) {
+	tests := []struct {
+		name string
+		cut  func(client, server *net.TCPConn)
+	}{
+		{"closed", func(client, server *net.TCPConn) { client.Close() }},
+		{"reset by the peer", func(client, server *net.TCPConn) {
+			server.SetLinger(0)
+			server.Close()
+			time.Sleep(20 * time.Millisecond)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := connPair(t)
+			tt.cut(client, server)
+			if err := probeConn(client); err == nil {
+				t.Error("probeConn() = nil on a lost connection")
+			}
+		})
+	}
+}
+
+func TestHTTPSinkHealthy(t *testing.T) {
+	rec := &httpRecorder{fail: []int{http.StatusServiceUnavailable}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	s := NewHTTPSink(srv.URL, WithRequestRetry(0, 0))
+	defer s.Close()
+	if err := s.Healthy(); err != nil {
+		t.Fatalf("Healthy() = %v before any request", err)
+	}
+	s.Write([]byte(`{"a":1}`))
+	s.Flush()
+	var se *httpStatusError
+	if err := s.Healthy(); !errors.As(err, &se) || se.status != http.StatusServiceUnavailable {
+		t.Errorf("Healthy() = %v after a failed request, want the 503", err)
+	}
+	s.Write([]byte(`{"a":1}`))
+	s.Flush()
+	if err := s.Healthy(); err != nil {
+		t.Errorf("Healthy() = %v after a successful request", err)
+	}
+}
+
+func TestConnSinksHealthy(t *testing.T) {
+	srv := newRESPServer(t)
+	s, err := NewRedisStreamSink("redis://"+srv.ln.Addr().String(), "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Healthy(); err != nil {
+		t.Errorf("Healthy() = %v while connected", err)
+	}
+	s.Close()
+	if err := s.Healthy(); !errors.Is(err, errNotConnected) {
+		t.Errorf("Healthy() after Close = %v, want errNotConnected", err)
+	}
+}
+
+func TestS3SinkHealthy(t *testing.T) {
+	store, s, now := newS3Test(t, WithS3ErrorHandler(func(error) {}))
+	store.setFail(true)
+	s.Write([]byte("a\n"))
+	*now = now.Add(time.Hour)
+	s.Write([]byte("b\n"))
+	waitFor(t, "failed upload", func() bool { return s.Healthy() != nil })
+	s.Close()
+	if err := s.Healthy(); !errors.Is(err, errS3Closed) {
+		t.Errorf("Healthy() after Close = %v, want errS3Closed", err)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"time"
+)
+
+const defaultHeartbeat = time.Minute
+
+type heartbeat struct {
+	interval time.Duration
+	level    Level
+}
+
+// WithHeartbeat makes New start a ticker logging runtime statistics at
+// lvl every interval, one minute when interval is not positive. It stops
+// when the logger is closed.
+func WithHeartbeat(interval time.Duration, lvl Level) Option {
+	if interval <= 0 {
+		interval = defaultHeartbeat
+	}
+	return func(o *options) {
+		o.heartbeat = &heartbeat{interval: interval, level: lvl}
+	}
+}
+
+func (l *Logger) startHeartbeat() {
+	hb := l.opt.heartbeat
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(hb.interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				l.WithFields(runtimeStats()).entry().write(hb.level, FmtEmptySeparate, "heartbeat")
+			case <-done:
+				return
+			}
+		}
+	}()
+	l.opt.closers = append(l.opt.closers, func() error {
+		close(done)
+		return nil
+	})
+}
+
+func runtimeStats() Fields {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	fields := Fields{
+		"goroutines":     runtime.NumGoroutine(),
+		"heap_inuse":     ms.HeapInuse,
+		"heap_alloc":     ms.HeapAlloc,
+		"num_gc":         ms.NumGC,
+		"gc_pause_last":  time.Duration(ms.PauseNs[(ms.NumGC+255)%256]).String(),
+		"gc_pause_total": time.Duration(ms.PauseTotalNs).String(),
+	}
+	if n := openFDs(); n >= 0 {
+		fields["open_fds"] = n
+	}
+	return fields
+}
+
+// openFDs counts the open file descriptors of the process, -1 when the
+// platform does not expose them.
+func openFDs() int {
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		if entries, err := os.ReadDir(dir); err == nil {
+			return len(entries)
+		}
+	}
+	return -1
+}
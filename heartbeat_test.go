@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuilder is a strings.Builder safe for the background goroutines
+// of a logger.
+type lockedBuilder struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (w *lockedBuilder) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.b.Write(p)
+}
+
+func (w *lockedBuilder) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.b.String()
+}
+
+func TestHeartbeatInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		want     time.Duration
+	}{
+		{"positive", time.Second, time.Second},
+		{"zero", 0, defaultHeartbeat},
+		{"negative", -time.Second, defaultHeartbeat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(WithPosition(&lockedBuilder{}), WithHeartbeat(tt.interval, InfoLevel))
+			defer l.Close()
+			if got := l.opt.heartbeat.interval; got != tt.want {
+				t.Errorf("interval = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeartbeatLogs(t *testing.T) {
+	out := &lockedBuilder{}
+	l := New(WithPosition(out), WithFormatter(&JSONFormatter{}), WithHeartbeat(5*time.Millisecond, WarnLevel))
+	waitFor(t, "heartbeat", func() bool { return strings.Contains(out.String(), `"message":"heartbeat"`) })
+	l.Close()
+	if !strings.Contains(out.String(), `"goroutines":`) || !strings.Contains(out.String(), `"level":"Warn"`) {
+		t.Errorf("heartbeat %s, want runtime statistics at the level", out.String())
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// httpValueLimit caps the length of URLs and header values in fields.
+const httpValueLimit = 1024
+
+// HTTPRequest returns the fields describing r, including the values of
+// the named headers. Credential headers are redacted and long values are
+// truncated.
+func HTTPRequest(r *http.Request, headers ...string) Fields {
+	fields := Fields{
+		"http.method":         r.Method,
+		"http.url":            truncate(r.URL.Redacted(), httpValueLimit),
+		"http.proto":          r.Proto,
+		"http.host":           r.Host,
+		"http.content_length": r.ContentLength,
+	}
+	if r.RemoteAddr != "" {
+		fields["http.remote_addr"] = r.RemoteAddr
+	}
+	headerFields(fields, "http.request.header.", r.Header, headers)
+	return fields
+}
+
+// HTTPResponse returns the fields describing resp, see HTTPRequest.
+func HTTPResponse(resp *http.Response, headers ...string) Fields {
+	fields := Fields{
+		"http.status":         resp.StatusCode,
+		"http.proto":          resp.Proto,
+		"http.content_length": resp.ContentLength,
+	}
+	headerFields(fields, "http.response.header.", resp.Header, headers)
+	return fields
+}
+
+func headerFields(fields Fields, prefix string, h http.Header, names []string) {
+	for _, name := range names {
+		name = textproto.CanonicalMIMEHeaderKey(name)
+		values, ok := h[name]
+		if !ok {
+			continue
+		}
+		key := prefix + strings.ToLower(name)
+		if isCredentialHeader(name) {
+			fields[key] = redacted
+			continue
+		}
+		fields[key] = truncate(strings.Join(values, ", "), httpValueLimit)
+	}
+}
+
+func isCredentialHeader(name string) bool {
+	for _, h := range defaultRedactHeaders {
+		if h == name {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	return s[:limit] + "..."
+}
@@ -0,0 +1,504 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	errEntryTooLarge = errors.New("logie: entry exceeds the request size limit")
+	errSendQueueFull = errors.New("logie: http send queue full, batch dropped")
+)
+
+// BatchFormat selects how HTTPSink joins the entries of a request.
+type BatchFormat uint8
+
+const (
+	// BatchNDJSON writes one entry per line.
+	BatchNDJSON BatchFormat = iota
+	// BatchJSONArray writes the entries as the elements of an array.
+	BatchJSONArray
+)
+
+type HTTPSinkOption func(*HTTPSink)
+
+// HTTPSink buffers entries and POSTs them in batches to an endpoint, a
+// batch is sent once it holds BatchSize entries, when the next entry would
+// push the request over MaxRequestSize and every FlushInterval. Failed
+// requests are retried on network errors, 429 and 5xx responses.
+//
+// Requests are sent by a background goroutine, Write only hands full
+// batches to a bounded queue. A batch is dropped while the queue is full,
+// see Dropped, Flush and Close wait for the queued ones.
+//
+// An entry template adapts the payload to the receiving API, e.g.
+// "{\"index\":{}}\n{{entry}}" for Elasticsearch _bulk or
+// "{\"event\":{{entry}}}" for Splunk HEC.
+type HTTPSink struct {
+	mu       sync.Mutex
+	endpoint string
+	client   *http.Client
+	header   http.Header
+	format   BatchFormat
+	template string
+	size     int
+	maxBytes int
+	interval time.Duration
+	attempts int
+	backoff  time.Duration
+	onError  func(error)
+	// wrap replaces the template for sinks built on HTTPSink, prepare
+	// completes each request and onResponse inspects successful responses
+	wrap       func(entry []byte) []byte
+	prepare    func(req *http.Request, body []byte) error
+	onResponse func(body []byte) error
+
+	batch   bytes.Buffer
+	entries int
+	// taken counts the entries of the body last returned by take
+	taken   int
+	queue   chan httpBatch
+	dropped uint64
+	done    chan struct{}
+	stopped chan struct{}
+	wg      sync.WaitGroup
+	closing sync.Once
+	// failed holds the error of the last request, nil once one succeeds
+	failMu sync.Mutex
+	failed error
+}
+
+// httpBatch is a request body waiting for the sender, reply is set by
+// Flush to learn the outcome.
+type httpBatch struct {
+	body  []byte
+	reply chan error
+}
+
+func WithBatchFormat(f BatchFormat) HTTPSinkOption {
+	return func(s *HTTPSink) {
+		s.format = f
+	}
+}
+
+// WithEntryTemplate wraps every entry in tmpl, whose {{entry}} is
+// replaced by the entry without its trailing newline.
+func WithEntryTemplate(tmpl string) HTTPSinkOption {
+	return func(s *HTTPSink) {
+		s.template = tmpl
+	}
+}
+
+func WithRequestHeader(key, value string) HTTPSinkOption {
+	return func(s *HTTPSink) {
+		s.header.Set(key, value)
+	}
+}
+
+func WithBearerToken(token string) HTTPSinkOption {
+	return WithRequestHeader("Authorization", "Bearer "+token)
+}
+
+func WithBasicAuth(user, password string) HTTPSinkOption {
+	return func(s *HTTPSink) {
+		r := &http.Request{Header: http.Header{}}
+		r.SetBasicAuth(user, password)
+		s.header.Set("Authorization", r.Header.Get("Authorization"))
+	}
+}
+
+func WithBatchSize(n int) HTTPSinkOption {
+	return func(s *HTTPSink) {
+		s.size = n
+	}
+}
+
+// WithMaxRequestSize caps the body of a request, larger entries are
+// rejected.
+func WithMaxRequestSize(size int) HTTPSinkOption {
+	return func(s *HTTPSink) {
+		s.maxBytes = size
+	}
+}
+
+// WithSendQueue sets how many full batches may wait for the sender, 4 by
+// default.
+func WithSendQueue(n int) HTTPSinkOption {
+	return func(s *HTTPSink) {
+		s.queue = make(chan httpBatch, n)
+	}
+}
+
+// WithFlushInterval sets how often a partial batch is sent, one second by
+// default or when d is not positive.
+func WithFlushInterval(d time.Duration) HTTPSinkOption {
+	return func(s *HTTPSink) {
+		s.interval = d
+	}
+}
+
+// WithRequestRetry retries a failed request up to attempts times,
+// doubling the backoff between tries.
+func WithRequestRetry(attempts int, backoff time.Duration) HTTPSinkOption {
+	return func(s *HTTPSink) {
+		s.attempts, s.backoff = attempts, backoff
+	}
+}
+
+func WithHTTPClient(c *http.Client) HTTPSinkOption {
+	return func(s *HTTPSink) {
+		s.client = c
+	}
+}
+
+// WithFlushErrorHandler receives the errors of background flushes.
+func WithFlushErrorHandler(fn func(error)) HTTPSinkOption {
+	return func(s *HTTPSink) {
+		s.onError = fn
+	}
+}
+
+func NewHTTPSink(endpoint string, opts ...HTTPSinkOption) *HTTPSink {
+	s := &HTTPSink{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		header:   http.Header{},
+		size:     500,
+		maxBytes: 5 << 20,
+		interval: time.Second,
+		attempts: 3,
+		backoff:  100 * time.Millisecond,
+		queue:    make(chan httpBatch, 4),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.interval <= 0 {
+		s.interval = time.Second
+	}
+	if s.header.Get("Content-Type") == "" {
+		if s.format == BatchJSONArray {
+			s.header.Set("Content-Type", "application/json")
+		} else {
+			s.header.Set("Content-Type", "application/x-ndjson")
+		}
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s
+}
+
+// run is the only goroutine sending requests, so batches are delivered in
+// order.
+func (s *HTTPSink) run() {
+	defer s.wg.Done()
+	defer close(s.stopped)
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		select {
+		case b := <-s.queue:
+			s.deliver(b)
+		case <-t.C:
+			s.mu.Lock()
+			body := s.take()
+			s.mu.Unlock()
+			if body != nil {
+				s.report(s.send(body))
+			}
+		case <-s.done:
+			for {
+				select {
+				case b := <-s.queue:
+					s.deliver(b)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *HTTPSink) deliver(b httpBatch) {
+	var err error
+	if b.body != nil {
+		err = s.send(b.body)
+	}
+	if b.reply != nil {
+		b.reply <- err
+	} else {
+		s.report(err)
+	}
+}
+
+func (s *HTTPSink) report(err error) {
+	if err != nil && s.onError != nil {
+		s.onError(err)
+	}
+}
+
+// Dropped returns the number of entries lost because the send queue was
+// full.
+func (s *HTTPSink) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+func (s *HTTPSink) Write(p []byte) (int, error) {
+	entry := bytes.TrimSuffix(p, []byte("\n"))
+	if s.wrap != nil {
+		entry = s.wrap(entry)
+	} else if s.template != "" {
+		entry = []byte(strings.ReplaceAll(s.template, "{{entry}}", string(entry)))
+	}
+	if len(entry)+2 > s.maxBytes {
+		return 0, errEntryTooLarge
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries > 0 && s.batch.Len()+len(entry)+2 > s.maxBytes {
+		s.enqueue(s.take())
+	}
+	if s.format == BatchJSONArray {
+		if s.entries > 0 {
+			s.batch.WriteByte(',')
+		} else {
+			s.batch.WriteByte('[')
+		}
+	}
+	s.batch.Write(entry)
+	if s.format == BatchNDJSON {
+		s.batch.WriteByte('\n')
+	}
+	s.entries++
+	if s.entries >= s.size {
+		s.enqueue(s.take())
+	}
+	return len(p), nil
+}
+
+// enqueue hands body to the sender without waiting, the caller holds s.mu
+// and s.entries still counts the entries of body.
+func (s *HTTPSink) enqueue(body []byte) {
+	select {
+	case s.queue <- httpBatch{body: body}:
+	default:
+		atomic.AddUint64(&s.dropped, uint64(s.taken))
+		s.report(errSendQueueFull)
+	}
+}
+
+// take returns the body of the pending batch and starts a new one, the
+// caller holds s.mu.
+func (s *HTTPSink) take() []byte {
+	s.taken = s.entries
+	if s.entries == 0 {
+		return nil
+	}
+	if s.format == BatchJSONArray {
+		s.batch.WriteByte(']')
+	}
+	body := append([]byte(nil), s.batch.Bytes()...)
+	s.batch.Reset()
+	s.entries = 0
+	return body
+}
+
+// Flush sends the queued batches and the pending entries, it returns the
+// error of the last request.
+func (s *HTTPSink) Flush() error {
+	s.mu.Lock()
+	body := s.take()
+	s.mu.Unlock()
+
+	b := httpBatch{body: body, reply: make(chan error, 1)}
+	select {
+	case s.queue <- b:
+	case <-s.stopped:
+		return s.sendClosed(body)
+	}
+	select {
+	case err := <-b.reply:
+		return err
+	case <-s.stopped:
+		// the sender may have stopped before reaching b, whoever takes
+		// it from the queue replies
+		_ = s.sendClosed(nil)
+		return <-b.reply
+	}
+}
+
+// sendClosed sends from the caller once the sender stopped, the batches
+// queued by later writes first.
+func (s *HTTPSink) sendClosed(body []byte) error {
+	for drained := false; !drained; {
+		select {
+		case b := <-s.queue:
+			s.deliver(b)
+		default:
+			drained = true
+		}
+	}
+	if body == nil {
+		return nil
+	}
+	return s.send(body)
+}
+
+// Close stops the background flushes and sends the pending entries.
+func (s *HTTPSink) Close() error {
+	var err error
+	s.closing.Do(func() {
+		err = s.Flush()
+		close(s.done)
+		s.wg.Wait()
+	})
+	return err
+}
+
+func (s *HTTPSink) send(body []byte) error {
+	wait := s.backoff
+	err := s.post(body)
+	for i := 0; i < s.attempts && err != nil && retryableHTTP(err); i++ {
+		time.Sleep(wait)
+		wait *= 2
+		err = s.post(body)
+	}
+	s.failMu.Lock()
+	s.failed = err
+	s.failMu.Unlock()
+	return err
+}
+
+// Healthy reports the error of the last request, until one succeeds.
+func (s *HTTPSink) Healthy() error {
+	s.failMu.Lock()
+	defer s.failMu.Unlock()
+	if s.failed != nil {
+		return fmt.Errorf("logie: last request to %s failed: %w", s.endpoint, s.failed)
+	}
+	return nil
+}
+
+type httpStatusError struct {
+	status int
+	body   string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("logie: http: %d %s: %s", e.status, http.StatusText(e.status), e.body)
+}
+
+func retryableHTTP(err error) bool {
+	var se *httpStatusError
+	if errors.As(err, &se) {
+		return se.status == http.StatusTooManyRequests || se.status >= 500
+	}
+	return true
+}
+
+func (s *HTTPSink) post(body []byte) error {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range s.header {
+		req.Header[k] = v
+	}
+	if s.prepare != nil {
+		if err := s.prepare(req, body); err != nil {
+			return err
+		}
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return &httpStatusError{status: resp.StatusCode, body: readLimited(resp.Body, 512)}
+	}
+	if s.onResponse != nil {
+		b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return err
+		}
+		return s.onResponse(b)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// openHTTPSink understands the format (ndjson, array), batch, max_size,
+// flush (a duration), token and template query parameters, plus the TLS
+// ones of the tls sink. They are removed from the endpoint URL.
+func openHTTPSink(u *url.URL) (io.Writer, error) {
+	q := u.Query()
+	var opts []HTTPSinkOption
+	switch q.Get("format") {
+	case "", "ndjson":
+	case "array":
+		opts = append(opts, WithBatchFormat(BatchJSONArray))
+	default:
+		return nil, fmt.Errorf("logie: invalid batch format %q", q.Get("format"))
+	}
+	if v := q.Get("batch"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("logie: invalid batch size %q", v)
+		}
+		opts = append(opts, WithBatchSize(n))
+	}
+	if v := q.Get("max_size"); v != "" {
+		size, err := parseSize(v)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithMaxRequestSize(int(size)))
+	}
+	if v := q.Get("flush"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("logie: invalid flush interval %q", v)
+		}
+		opts = append(opts, WithFlushInterval(d))
+	}
+	if v := q.Get("token"); v != "" {
+		opts = append(opts, WithBearerToken(v))
+	}
+	if v := q.Get("template"); v != "" {
+		opts = append(opts, WithEntryTemplate(v))
+	}
+	if u.Scheme == "https" {
+		c, err := tlsConfigFromQuery(q)
+		if err != nil {
+			return nil, err
+		}
+		cfg, err := c.Build()
+		if err != nil {
+			return nil, err
+		}
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		tr.TLSClientConfig = cfg
+		opts = append(opts, WithHTTPClient(&http.Client{Transport: tr, Timeout: 30 * time.Second}))
+	}
+
+	for _, k := range []string{"format", "batch", "max_size", "flush", "token", "template", "ca", "cert", "key", "sni", "min_version", "reload", "insecure"} {
+		q.Del(k)
+	}
+	endpoint := *u
+	endpoint.RawQuery = q.Encode()
+	return NewHTTPSink(endpoint.String(), opts...), nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// httpRecorder is a server keeping the request bodies it accepted, it
+// answers with the statuses of fail first.
+type httpRecorder struct {
+	mu     sync.Mutex
+	bodies []string
+	header []http.Header
+	fail   []int
+}
+
+func (h *httpRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.fail) > 0 {
+		status := h.fail[0]
+		h.fail = h.fail[1:]
+		w.WriteHeader(status)
+		return
+	}
+	h.bodies = append(h.bodies, string(body))
+	h.header = append(h.header, r.Header)
+}
+
+func (h *httpRecorder) got() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.bodies...)
+}
+
+func TestHTTPSinkBatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []HTTPSinkOption
+		entries []string
+		want    []string
+	}{
+		{
+			name:    "ndjson",
+			opts:    []HTTPSinkOption{WithBatchSize(2)},
+			entries: []string{`{"a":1}` + "\n", `{"b":2}` + "\n", `{"c":3}` + "\n"},
+			want:    []string{`{"a":1}` + "\n" + `{"b":2}` + "\n", `{"c":3}` + "\n"},
+		},
+		{
+			name:    "array",
+			opts:    []HTTPSinkOption{WithBatchFormat(BatchJSONArray)},
+			entries: []string{`{"a":1}` + "\n", `{"b":2}` + "\n"},
+			want:    []string{`[{"a":1},{"b":2}]`},
+		},
+		{
+			name:    "template",
+			opts:    []HTTPSinkOption{WithEntryTemplate(`{"event":{{entry}}}`)},
+			entries: []string{`{"a":1}` + "\n"},
+			want:    []string{`{"event":{"a":1}}` + "\n"},
+		},
+		{
+			name:    "request size",
+			opts:    []HTTPSinkOption{WithMaxRequestSize(20)},
+			entries: []string{`{"a":1}` + "\n", `{"b":2}` + "\n", `{"c":3}` + "\n"},
+			want:    []string{`{"a":1}` + "\n" + `{"b":2}` + "\n", `{"c":3}` + "\n"},
+		},
+		{
+			name:    "retried server error",
+			opts:    []HTTPSinkOption{WithRequestRetry(2, time.Millisecond)},
+			entries: []string{`{"a":1}` + "\n"},
+			want:    []string{`{"a":1}` + "\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &httpRecorder{}
+			if tt.name == "retried server error" {
+				rec.fail = []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}
+			}
+			srv := httptest.NewServer(rec)
+			defer srv.Close()
+			s := NewHTTPSink(srv.URL, tt.opts...)
+			for _, e := range tt.entries {
+				if _, err := s.Write([]byte(e)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+			got := rec.got()
+			if len(got) != len(tt.want) {
+				t.Fatalf("requests %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("request %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPSinkRejects(t *testing.T) {
+	rec := &httpRecorder{fail: []int{http.StatusBadRequest}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	s := NewHTTPSink(srv.URL, WithMaxRequestSize(16), WithRequestRetry(3, time.Millisecond))
+	if _, err := s.Write([]byte(`{"message":"too large"}`)); err != errEntryTooLarge {
+		t.Errorf("Write() error = %v, want errEntryTooLarge", err)
+	}
+	s.Write([]byte(`{"a":1}`))
+	if err := s.Close(); err == nil {
+		t.Error("Close() = nil after a 400, want the error")
+	}
+	if len(rec.got()) != 0 {
+		t.Errorf("a 400 was retried")
+	}
+}
+
+func TestHTTPSinkFlushInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+	}{
+		{"short", 10 * time.Millisecond},
+		{"zero defaults", 0},
+		{"negative defaults", -time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &httpRecorder{}
+			srv := httptest.NewServer(rec)
+			defer srv.Close()
+			s := NewHTTPSink(srv.URL, WithFlushInterval(tt.interval))
+			defer s.Close()
+			s.Write([]byte(`{"a":1}` + "\n"))
+			waitFor(t, "flush", func() bool { return len(rec.got()) == 1 })
+		})
+	}
+}
+
+func TestHTTPSinkConcurrentClose(t *testing.T) {
+	rec := &httpRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	s := NewHTTPSink(srv.URL)
+	s.Write([]byte(`{"a":1}` + "\n"))
+
+	var wg sync.WaitGroup
+	var panics int32
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if recover() != nil {
+					atomic.AddInt32(&panics, 1)
+				}
+			}()
+			s.Close()
+		}()
+	}
+	wg.Wait()
+	if panics != 0 {
+		t.Fatalf("%d Close calls panicked", panics)
+	}
+	if got := rec.got(); len(got) != 1 {
+		t.Errorf("requests %q, want the pending entry once", got)
+	}
+	// writes after Close are sent by the caller
+	s.Write([]byte(`{"b":2}` + "\n"))
+	if err := s.Flush(); err != nil || len(rec.got()) != 2 {
+		t.Errorf("Flush() after Close = %v, requests %q", err, rec.got())
+	}
+}
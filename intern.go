@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strconv"
+	"sync"
+)
+
+const (
+	internMaxEntries = 4096
+	internMaxLen     = 64
+)
+
+// interner deduplicates short strings produced again and again for each
+// entry, such as normalized field keys, so they are allocated once.
+type interner struct {
+	mu      sync.RWMutex
+	strings map[string]string
+}
+
+type callSite struct {
+	file string
+	line int
+}
+
+// callSites interns the "file:line" values of the JSON file field.
+var callSites = struct {
+	sync.RWMutex
+	m map[callSite]string
+}{m: make(map[callSite]string)}
+
+func callSiteString(file string, line int) string {
+	key := callSite{file, line}
+	callSites.RLock()
+	s, ok := callSites.m[key]
+	callSites.RUnlock()
+	if ok {
+		return s
+	}
+
+	s = file + ":" + strconv.Itoa(line)
+	callSites.Lock()
+	if len(callSites.m) >= internMaxEntries {
+		callSites.m = make(map[callSite]string)
+	}
+	callSites.m[key] = s
+	callSites.Unlock()
+	return s
+}
+
+// intern returns the canonical copy of the string built by fn for s, fn is
+// only called on a miss.
+func (in *interner) intern(s string, fn func(string) string) string {
+	if len(s) > internMaxLen {
+		return fn(s)
+	}
+	in.mu.RLock()
+	v, ok := in.strings[s]
+	in.mu.RUnlock()
+	if ok {
+		return v
+	}
+
+	v = fn(s)
+	in.mu.Lock()
+	if len(in.strings) >= internMaxEntries {
+		in.strings = make(map[string]string)
+	}
+	in.strings[s] = v
+	in.mu.Unlock()
+	return v
+}
@@ -0,0 +1,26 @@
+//go:build jsoniter
+
+package main
+
+import (
+	"io"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// encodeJSON writes v followed by a newline through a pooled stream,
+// avoiding an encoder allocation per entry.
+func encodeJSON(w io.Writer, v any) error {
+	stream := jsoniter.ConfigDefault.BorrowStream(w)
+	defer jsoniter.ConfigDefault.ReturnStream(stream)
+	stream.WriteVal(v)
+	stream.WriteRaw("\n")
+	if stream.Error != nil {
+		return stream.Error
+	}
+	return stream.Flush()
+}
+
+func decodeJSON(data []byte, v any) error {
+	return jsoniter.Unmarshal(data, v)
+}
@@ -0,0 +1,17 @@
+//go:build !jsoniter
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// encodeJSON writes v followed by a newline.
+func encodeJSON(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func decodeJSON(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
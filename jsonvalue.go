@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"reflect"
+	"time"
+)
+
+// DurationEncoding selects how JSONFormatter writes time.Duration values.
+type DurationEncoding uint8
+
+const (
+	// DurationNanos writes the number of nanoseconds.
+	DurationNanos DurationEncoding = iota
+	// DurationString writes the value of Duration.String, e.g. "1.5s".
+	DurationString
+	// DurationSeconds writes the number of seconds as a float.
+	DurationSeconds
+)
+
+// BytesEncoding selects how JSONFormatter writes []byte values.
+type BytesEncoding uint8
+
+const (
+	BytesBase64 BytesEncoding = iota
+	BytesString
+	BytesHex
+)
+
+// NilEncoding selects how JSONFormatter writes nil field values.
+type NilEncoding uint8
+
+const (
+	NilNull NilEncoding = iota
+	// NilOmit leaves the field out of the entry.
+	NilOmit
+)
+
+// value applies the encoding policies of f to a field or argument, only
+// top level values are converted. It reports false for a nil value under
+// NilOmit.
+func (f *JSONFormatter) value(v any) (any, bool) {
+	switch val := v.(type) {
+	case nil:
+		return nil, f.Nils != NilOmit
+	case time.Time:
+		if f.TimeLayout != "" {
+			return val.Format(f.TimeLayout), true
+		}
+	case time.Duration:
+		switch f.Durations {
+		case DurationString:
+			return val.String(), true
+		case DurationSeconds:
+			return val.Seconds(), true
+		}
+	case []byte:
+		if val == nil {
+			return nil, f.Nils != NilOmit
+		}
+		switch f.Bytes {
+		case BytesString:
+			return string(val), true
+		case BytesHex:
+			return hex.EncodeToString(val), true
+		}
+		return base64.StdEncoding.EncodeToString(val), true
+	}
+	if f.Nils == NilOmit && isNil(v) {
+		return nil, false
+	}
+	return v, true
+}
+
+func isNil(v any) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface, reflect.Func, reflect.Chan:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package main
+
+import "time"
+
+// Since returns the latency fields of the time elapsed since start, see
+// Latency.
+func Since(start time.Time) Fields {
+	return Latency(start, time.Now())
+}
+
+// Latency returns the time between start and end as "latency", human
+// readable, and "latency_ms", a number. Times from time.Now carry a
+// monotonic reading that wall clock adjustments do not affect, without
+// it a negative latency is reported as zero with "latency_skewed".
+func Latency(start, end time.Time) Fields {
+	d := end.Sub(start)
+	fields := Fields{}
+	if d < 0 {
+		d = 0
+		fields["latency_skewed"] = true
+	}
+	fields["latency"] = d.String()
+	fields["latency_ms"] = float64(d) / float64(time.Millisecond)
+	return fields
+}
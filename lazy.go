@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Arguments and field values are kept as given until the formatter runs,
+// which only happens once the level and sampling checks passed, so the
+// String, Error and MarshalJSON methods of filtered entries are never
+// called. A panic inside one of them is recovered by Entry.format: the
+// offending values are replaced by a placeholder, the entry is formatted
+// again and a diagnostic names the call site.
+
+// defuse replaces the values of e whose methods panic and returns how
+// many it found.
+func (e *Entry) defuse() int {
+	site := "unknown"
+	if e.File != "" {
+		site = callSiteString(e.File, e.Line)
+	}
+
+	n, copied := 0, false
+	for i, arg := range e.Args {
+		if r := probeValue(arg); r != nil {
+			if !copied {
+				e.Args = append([]any(nil), e.Args...)
+				copied = true
+			}
+			e.Args[i] = panicPlaceholder(arg, r)
+			n++
+			e.reportPanic(site, fmt.Sprintf("argument %d", i+1), arg, r)
+		}
+	}
+	for k, v := range e.Fields {
+		if r := probeValue(v); r != nil {
+			e.SetField(k, panicPlaceholder(v, r))
+			n++
+			e.reportPanic(site, "field "+k, v, r)
+		}
+	}
+	return n
+}
+
+func (e *Entry) reportPanic(site, what string, v, r any) {
+	// the logger fields may hold the panicking value itself
+	l := e.logger.clone()
+	l.fields = nil
+	l.diagnose("logie: value method panicked", Fields{
+		"call_site":   site,
+		"value":       what,
+		"type":        fmt.Sprintf("%T", v),
+		"panic":       true,
+		"panic_value": fmt.Sprint(r),
+	})
+}
+
+// probeValue calls the methods a formatter may call on v and returns what they
+// panicked with, or nil.
+func probeValue(v any) (r any) {
+	defer func() {
+		r = recover()
+	}()
+	if m, ok := v.(json.Marshaler); ok {
+		_, _ = m.MarshalJSON()
+	}
+	switch val := v.(type) {
+	case Valuer:
+		_ = val.LogieValue()
+	case error:
+		_ = val.Error()
+	case fmt.Stringer:
+		_ = val.String()
+	}
+	return nil
+}
+
+func panicPlaceholder(v, r any) string {
+	return fmt.Sprintf("%%!v(PANIC=%T: %v)", v, r)
+}
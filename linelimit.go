@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// CRILineSize is the line length above which Docker and CRI runtimes
+// split a log line into partial lines.
+const CRILineSize = 16 << 10
+
+const (
+	// TruncatedMarker ends an entry cut by WithMaxLineSize.
+	TruncatedMarker = "...[truncated]"
+	// ContinuationMarker ends every line but the last of a split entry.
+	ContinuationMarker = "\\"
+)
+
+type lineLimit struct {
+	size  int
+	split bool
+}
+
+// WithMaxLineSize keeps formatted entries, newline included, within size
+// bytes so container runtimes never split them, see CRILineSize. Longer
+// entries are truncated with TruncatedMarker, or with split set cut into
+// lines ending with ContinuationMarker except the last. Either way the
+// entry is still written at once.
+func WithMaxLineSize(size int, split bool) Option {
+	return func(o *options) {
+		if size < 2*len(TruncatedMarker) {
+			size = 2 * len(TruncatedMarker)
+		}
+		o.lineLimit = &lineLimit{size: size, split: split}
+	}
+}
+
+func (e *Entry) limitLine() {
+	ll := e.logger.opt.lineLimit
+	if ll == nil || e.Buf.Len() <= ll.size {
+		return
+	}
+	line := bytes.TrimSuffix(e.Buf.Bytes(), []byte("\n"))
+	out := make([]byte, 0, e.Buf.Len()+len(line)/ll.size*(len(ContinuationMarker)+1))
+
+	if !ll.split {
+		cut := utf8Cut(line, ll.size-len(TruncatedMarker)-1)
+		out = append(out, line[:cut]...)
+		out = append(out, TruncatedMarker+"\n"...)
+	} else {
+		for len(line) > ll.size-1 {
+			cut := utf8Cut(line, ll.size-len(ContinuationMarker)-1)
+			out = append(out, line[:cut]...)
+			out = append(out, ContinuationMarker+"\n"...)
+			line = line[cut:]
+		}
+		out = append(out, line...)
+		out = append(out, '\n')
+	}
+	e.Buf.Reset()
+	e.Buf.Write(out)
+}
+
+// utf8Cut returns the largest length up to n not splitting a rune of b.
+func utf8Cut(b []byte, n int) int {
+	if n >= len(b) {
+		return len(b)
+	}
+	for n > 0 && !utf8.RuneStart(b[n]) {
+		n--
+	}
+	return n
+}
@@ -0,0 +1,58 @@
+package main
+
+// MessageCatalog returns the localized text of the message or format id,
+// ok is false for unknown ids.
+type MessageCatalog func(id string) (text string, ok bool)
+
+// WithLevelNames replaces the level names printed by TextFormatter, for
+// consoles read in another language. JSONFormatter keeps the names of
+// LevelMapping so pipelines keep working.
+func WithLevelNames(names map[Level]string) Option {
+	return func(o *options) {
+		o.levelNames = names
+	}
+}
+
+// WithMessageCatalog logs messages as ids translated by catalog: an
+// entry whose message, or format for the f methods, is known to the
+// catalog is written with the localized text and the stable id in the
+// msg_id field.
+func WithMessageCatalog(catalog MessageCatalog) Option {
+	return func(o *options) {
+		o.catalog = catalog
+	}
+}
+
+// levelName returns the name of the entry level for operators.
+func (e *Entry) levelName() string {
+	if name, ok := e.logger.opt.levelNames[e.Level]; ok {
+		return name
+	}
+	return LevelMapping[e.Level]
+}
+
+func (e *Entry) localize() {
+	catalog := e.logger.opt.catalog
+	if catalog == nil {
+		return
+	}
+	var id string
+	if e.Format != FmtEmptySeparate {
+		id = e.Format
+	} else if len(e.Args) == 1 {
+		id, _ = e.Args[0].(string)
+	}
+	if id == "" {
+		return
+	}
+	text, ok := catalog(id)
+	if !ok {
+		return
+	}
+	if e.Format != FmtEmptySeparate {
+		e.Format = text
+	} else {
+		e.Args = []any{text}
+	}
+	e.Fields = mergeFields(e.Fields, Fields{"msg_id": id})
+}
@@ -2,19 +2,16 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"runtime"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
-	"unsafe"
-
-	jsoniter "github.com/json-iterator/go"
 )
 
 var std = New()
@@ -53,28 +50,124 @@ type Formatter interface {
 	Format(entry *Entry) error
 }
 
+// LevelWriter is implemented by outputs that want to know the level of
+// the entry being written.
+type LevelWriter interface {
+	io.Writer
+	WriteLevel(lvl Level, p []byte) (int, error)
+}
+
 type options struct {
-	position     io.Writer
-	level        Level
-	stdLevel     Level
-	formatter    Formatter
-	enableCaller bool
+	position      io.Writer
+	level         Level
+	stdLevel      Level
+	formatter     Formatter
+	disableCaller bool
+	retry         *retryPolicy
+	deadLetter    *deadLetter
+	normalizer    *keyNormalizer
+	ctxFields     func(ctx context.Context) Fields
+
+	eventPosition io.Writer
+	quota         *quota
+	secrets       *secretScanner
+	pseudonymizer *pseudonymizer
+	anonymizeIP   map[string]struct{}
+	closers       []func() error
+	banner        bool
+	heartbeat     *heartbeat
+	retention     []retention
+	onError       func(err error)
+	fallback      io.Writer
+	watchdog      *watchdog
+	writeTimeout  time.Duration
+	stacktrace    bool
+	stackLevel    Level
+	sampler       *sampler
+	async         *asyncWriter
+	formatCheck   bool
+	failure       *failure
+	clock         func() time.Time
+	observer      *Observer
+	msgCache      *msgCache
+	zeroCopyWrite bool
+	fingerprint   bool
+	routes        []*Route
+	outputFunc    func(lvl Level) io.Writer
+	// outputName names the output in Stats instead of its description
+	outputName   string
+	dynamicLevel func(ctx context.Context) Level
+	production   bool
+	lineLimit    *lineLimit
+	stats        *stats
+	volume       *volumeTracker
+	shed         *shedder
+	fatalHooks   []func()
+	fatalCode    int
+	partition    *partitioner
+	schemas      []FieldSchema
+	levelNames   map[Level]string
+	catalog      MessageCatalog
+	runbooks     map[string]string
+	structDepth  int
+	middleware   []func(next EntryHandler) EntryHandler
+	handler      EntryHandler
+	sanity       SanityCheck
 }
 
 type Logger struct {
 	opt       *options
-	mu        sync.Mutex
+	mu        *sync.Mutex
 	entryPool *sync.Pool
+	fields    Fields
+	name      string
 }
 
 func New(opts ...Option) *Logger {
-	logger := &Logger{opt: initOptions(opts...)}
+	logger := &Logger{opt: initOptions(opts...), mu: new(sync.Mutex)}
+	logger.entryPool = &sync.Pool{New: func() interface{} {
+		return entry(logger)
+	}}
+	if logger.opt.banner {
+		logger.Banner()
+	}
+	if logger.opt.sanity != 0 {
+		logger.checkSanity()
+	}
+	if logger.opt.heartbeat != nil {
+		logger.startHeartbeat()
+	}
+	if logger.opt.async != nil {
+		logger.startAsync()
+	}
+	if logger.opt.retry != nil {
+		logger.startRetry()
+	}
+	for _, r := range logger.opt.retention {
+		logger.startRetention(r)
+	}
+	return logger
+}
+
+// clone returns a logger sharing options, lock and output with l.
+func (l *Logger) clone() *Logger {
+	logger := &Logger{opt: l.opt, mu: l.mu, fields: l.fields, name: l.name}
 	logger.entryPool = &sync.Pool{New: func() interface{} {
 		return entry(logger)
 	}}
 	return logger
 }
 
+// derive returns a clone of l with its own copy of the options, changed
+// by fn, so internal entries can bypass level or output settings.
+func (l *Logger) derive(fn func(o *options)) *Logger {
+	logger := l.clone()
+	opt := *l.opt
+	fn(&opt)
+	logger.opt = &opt
+	return logger
+}
+
 func StdLogger() *Logger {
 	return std
 }
@@ -99,120 +192,189 @@ func (l *Logger) Writer() io.Writer {
 	return l
 }
 
+// Write logs data as a single entry at the std level, dropping one
+// trailing newline. data is copied, see WithZeroCopyWrite.
 func (l *Logger) Write(data []byte) (int, error) {
-	l.entry().write(l.opt.stdLevel, FmtEmptySeparate, *(*string)(unsafe.Pointer(&data)))
-	return 0, nil
+	n := len(data)
+	if !l.enabled(l.opt.stdLevel) {
+		return n, nil
+	}
+	data = bytes.TrimSuffix(data, []byte("\n"))
+	l.entry().write(l.opt.stdLevel, FmtEmptySeparate, l.bridgeString(data))
+	return n, nil
 }
 
 func (l *Logger) entry() *Entry {
 	return l.entryPool.Get().(*Entry)
 }
 
+// enabled is checked before taking an entry from the pool, so filtered
+// calls cost neither pool traffic nor allocations. write copies args into
+// the entry, the variadic slice of the callers stays on their stack.
+func (l *Logger) enabled(lvl Level) bool {
+	if l.opt.shed != nil {
+		return l.shedLevel(l.opt.level) <= lvl
+	}
+	return l.opt.level <= lvl
+}
+
 func (l *Logger) Debug(args ...any) {
-	l.entry().write(DebugLevel, FmtEmptySeparate, args...)
+	if l.enabled(DebugLevel) {
+		l.entry().write(DebugLevel, FmtEmptySeparate, args...)
+	}
 }
 
 func (l *Logger) Info(args ...any) {
-	l.entry().write(InfoLevel, FmtEmptySeparate, args...)
+	if l.enabled(InfoLevel) {
+		l.entry().write(InfoLevel, FmtEmptySeparate, args...)
+	}
 }
 
 func (l *Logger) Warn(args ...any) {
-	l.entry().write(WarnLevel, FmtEmptySeparate, args...)
+	if l.enabled(WarnLevel) {
+		l.entry().write(WarnLevel, FmtEmptySeparate, args...)
+	}
 }
 
 func (l *Logger) Error(args ...any) {
-	l.entry().write(ErrorLevel, FmtEmptySeparate, args...)
+	if l.enabled(ErrorLevel) {
+		l.entry().write(ErrorLevel, FmtEmptySeparate, args...)
+	}
 }
 
 func (l *Logger) Panic(args ...any) {
-	l.entry().write(PanicLevel, FmtEmptySeparate, args...)
+	if l.enabled(PanicLevel) {
+		l.entry().write(PanicLevel, FmtEmptySeparate, args...)
+	}
 	panic(fmt.Sprint(args...))
 }
 
 func (l *Logger) Fatal(args ...any) {
-	l.entry().write(FatalLevel, FmtEmptySeparate, args...)
-	os.Exit(1)
+	if l.enabled(FatalLevel) {
+		l.entry().write(FatalLevel, FmtEmptySeparate, args...)
+	}
+	l.exit(l.opt.fatalCode)
 }
 
 func (l *Logger) Debugf(format string, args ...any) {
-	l.entry().write(DebugLevel, format, args...)
+	if l.enabled(DebugLevel) {
+		l.entry().write(DebugLevel, format, args...)
+	}
 }
 
 func (l *Logger) Infof(format string, args ...any) {
-	l.entry().write(InfoLevel, format, args...)
+	if l.enabled(InfoLevel) {
+		l.entry().write(InfoLevel, format, args...)
+	}
 }
 
 func (l *Logger) Warnf(format string, args ...any) {
-	l.entry().write(WarnLevel, format, args...)
+	if l.enabled(WarnLevel) {
+		l.entry().write(WarnLevel, format, args...)
+	}
 }
 
 func (l *Logger) Errorf(format string, args ...any) {
-	l.entry().write(ErrorLevel, format, args...)
+	if l.enabled(ErrorLevel) {
+		l.entry().write(ErrorLevel, format, args...)
+	}
 }
 
 func (l *Logger) Panicf(format string, args ...any) {
-	l.entry().write(PanicLevel, format, args...)
+	if l.enabled(PanicLevel) {
+		l.entry().write(PanicLevel, format, args...)
+	}
 	panic(fmt.Sprintf(format, args...))
 }
 
 func (l *Logger) Fatalf(format string, args ...any) {
-	l.entry().write(FatalLevel, format, args...)
-	os.Exit(1)
+	if l.enabled(FatalLevel) {
+		l.entry().write(FatalLevel, format, args...)
+	}
+	l.exit(l.opt.fatalCode)
 }
 
 // std logger
 func Debug(args ...any) {
-	std.entry().write(DebugLevel, FmtEmptySeparate, args...)
+	if std.enabled(DebugLevel) {
+		std.entry().write(DebugLevel, FmtEmptySeparate, args...)
+	}
 }
 
 func Info(args ...any) {
-	std.entry().write(InfoLevel, FmtEmptySeparate, args...)
+	if std.enabled(InfoLevel) {
+		std.entry().write(InfoLevel, FmtEmptySeparate, args...)
+	}
 }
 
 func Warn(args ...any) {
-	std.entry().write(WarnLevel, FmtEmptySeparate, args...)
+	if std.enabled(WarnLevel) {
+		std.entry().write(WarnLevel, FmtEmptySeparate, args...)
+	}
 }
 
 func Error(args ...any) {
-	std.entry().write(ErrorLevel, FmtEmptySeparate, args...)
+	if std.enabled(ErrorLevel) {
+		std.entry().write(ErrorLevel, FmtEmptySeparate, args...)
+	}
 }
 
 func Panic(args ...any) {
-	std.entry().write(PanicLevel, FmtEmptySeparate, args...)
+	if std.enabled(PanicLevel) {
+		std.entry().write(PanicLevel, FmtEmptySeparate, args...)
+	}
 	panic(fmt.Sprint(args...))
 }
 
 func Fatal(args ...any) {
-	std.entry().write(FatalLevel, FmtEmptySeparate, args...)
-	os.Exit(1)
+	if std.enabled(FatalLevel) {
+		std.entry().write(FatalLevel, FmtEmptySeparate, args...)
+	}
+	std.exit(std.opt.fatalCode)
 }
 
 func Debugf(format string, args ...any) {
-	std.entry().write(DebugLevel, format, args...)
+	if std.enabled(DebugLevel) {
+		std.entry().write(DebugLevel, format, args...)
+	}
 }
 
 func Infof(format string, args ...any) {
-	std.entry().write(InfoLevel, format, args...)
+	if std.enabled(InfoLevel) {
+		std.entry().write(InfoLevel, format, args...)
+	}
 }
 
 func Warnf(format string, args ...any) {
-	std.entry().write(WarnLevel, format, args...)
+	if std.enabled(WarnLevel) {
+		std.entry().write(WarnLevel, format, args...)
+	}
 }
 
 func Errorf(format string, args ...any) {
-	std.entry().write(ErrorLevel, format, args...)
+	if std.enabled(ErrorLevel) {
+		std.entry().write(ErrorLevel, format, args...)
+	}
 }
 
 func Panicf(format string, args ...any) {
-	std.entry().write(PanicLevel, format, args...)
+	if std.enabled(PanicLevel) {
+		std.entry().write(PanicLevel, format, args...)
+	}
 	panic(fmt.Sprintf(format, args...))
 }
 
 func Fatalf(format string, args ...any) {
-	std.entry().write(FatalLevel, format, args...)
-	os.Exit(1)
+	if std.enabled(FatalLevel) {
+		std.entry().write(FatalLevel, format, args...)
+	}
+	std.exit(std.opt.fatalCode)
 }
 
+// Entry is a single log call on its way to the output. Entries are pooled
+// and reset once written: hooks and formatters may read every field, but
+// may only change Fields through SetField and DeleteField, and must call
+// Clone to keep an entry past their return.
 type Entry struct {
 	logger *Logger
 	Buf    *bytes.Buffer
@@ -224,6 +386,20 @@ type Entry struct {
 	Func   string
 	Format string
 	Args   []any
+	Fields Fields
+	// Context is set by the *Ctx methods, it may be nil.
+	Context context.Context
+
+	// extra holds the fields given to a single call
+	extra Fields
+	// ownFields is set once Fields is a private copy of the entry
+	ownFields bool
+	// caller is the calling function kept for the fingerprint when
+	// caller capture is off
+	caller string
+	// args backs Args, copying into it keeps the variadic slice of the
+	// callers on their stack
+	args []any
 }
 
 func entry(logger *Logger) *Entry {
@@ -235,16 +411,45 @@ func entry(logger *Logger) *Entry {
 }
 
 func (e *Entry) write(lvl Level, format string, args ...any) {
-	if e.logger.opt.level > lvl {
+	if e.logger.levelFor(e.Context) > lvl {
+		e.release()
+		return
+	}
+	if s := e.logger.opt.sampler; s != nil && !s.allow(e.logger.now(), lvl, format, args) {
+		e.release()
 		return
 	}
-	e.Time = time.Now()
+	e.Time = e.logger.now()
 	e.Level = lvl
 	e.Format = format
-	e.Args = args
+	e.args = append(e.args[:0], args...)
+	e.Args = e.args
+	if e.logger.opt.formatCheck {
+		if format != FmtEmptySeparate {
+			e.checkFormat(2)
+		} else {
+			e.checkPrint(2)
+		}
+	}
+	fields := e.logger.fields
+	if len(e.extra) > 0 {
+		fields = mergeFields(fields, e.extra)
+	}
+	if e.Context != nil {
+		fields = e.logger.contextFields(e.Context, fields)
+	}
+	if d := e.logger.opt.structDepth; d > 0 {
+		fields = e.expandStructs(fields, d)
+	}
+	if e.logger.opt.stacktrace && lvl >= e.logger.opt.stackLevel {
+		fields = withStack(fields)
+	}
+	e.Fields = e.logger.normalize(e.logger.prepareFields(fields))
+	if len(e.logger.opt.schemas) > 0 {
+		e.checkSchema(2)
+	}
 
-	// TODO
-	if !e.logger.opt.enableCaller {
+	if !e.logger.opt.disableCaller {
 		if pc, file, line, ok := runtime.Caller(2); !ok {
 			e.File = "unknown"
 			e.Func = "unknown"
@@ -252,36 +457,129 @@ func (e *Entry) write(lvl Level, format string, args ...any) {
 			e.File, e.Line, e.Func = file, line, runtime.FuncForPC(pc).Name()
 			e.Func = e.Func[strings.LastIndex(e.Func, "/")+1:]
 		}
+	} else if e.logger.opt.fingerprint && lvl >= ErrorLevel {
+		if pc, _, _, ok := runtime.Caller(2); ok {
+			e.caller = runtime.FuncForPC(pc).Name()
+			e.caller = e.caller[strings.LastIndex(e.caller, "/")+1:]
+		}
+	}
+
+	if h := e.logger.opt.handler; h != nil {
+		if err := h(e); err != nil {
+			e.logger.reportError(err)
+		}
+	} else {
+		e.process()
 	}
+	e.release()
+}
 
+func (e *Entry) process() {
+	// the fingerprint hashes the untranslated message, it is the same in
+	// every locale
+	e.addFingerprint()
+	e.localize()
+	e.addRunbook()
+	e.addPartition()
 	e.format()
+	e.limitLine()
+	e.trackVolume()
+	e.observe()
+	e.route()
 	e.writer()
-	e.release()
+	e.checkFailure()
 }
 
+// format never panics: values whose methods panic are defused, a panic
+// of the formatter itself is reported through OnError and the entry is
+// written by a plain TextFormatter instead.
 func (e *Entry) format() {
-	_ = e.logger.opt.formatter.Format(e)
+	perr := e.tryFormat(e.logger.opt.formatter, true)
+	if perr == nil {
+		return
+	}
+	if e.defuse() > 0 {
+		e.resetOutput()
+		if perr = e.tryFormat(e.logger.opt.formatter, true); perr == nil {
+			return
+		}
+	}
+	e.logger.reportError(perr)
+	e.resetOutput()
+	if e.tryFormat(&TextFormatter{}, false) != nil {
+		e.resetOutput()
+	}
+}
+
+func (e *Entry) tryFormat(f Formatter, cache bool) (perr *PanicError) {
+	defer func() {
+		if r := recover(); r != nil {
+			perr = newPanicError("formatter", r)
+		}
+	}()
+	if c := e.logger.opt.msgCache; c != nil && cache {
+		if key, ok := c.key(e); ok {
+			if !c.format(e, key) {
+				if err := f.Format(e); err != nil {
+					e.logger.reportError(err)
+				} else {
+					c.store(e, key)
+				}
+			}
+			e.scanSecrets()
+			return nil
+		}
+	}
+	if err := f.Format(e); err != nil {
+		e.logger.reportError(err)
+	}
+	e.scanSecrets()
+	return nil
+}
+
+func (e *Entry) resetOutput() {
+	e.Buf.Reset()
+	for k := range e.Map {
+		delete(e.Map, k)
+	}
 }
 
 func (e *Entry) writer() {
-	e.logger.mu.Lock()
-	_, _ = e.logger.opt.position.Write(e.Buf.Bytes())
-	e.logger.mu.Unlock()
+	if e.overQuota() || e.logger.enqueue(e) {
+		return
+	}
+	err := e.logger.outputSync(e.Context, e.Level, e.Buf.Bytes())
+	if err != nil {
+		e.logger.reportError(err)
+	}
 }
 
 func (e *Entry) release() {
-	e.Args, e.Line, e.File, e.Format, e.Func = nil, 0, "", "", ""
+	for i := range e.args {
+		e.args[i] = nil
+	}
+	e.Args, e.Line, e.File, e.Format, e.Func, e.caller = nil, 0, "", "", "", ""
+	e.Fields, e.Context, e.extra, e.ownFields = nil, nil, nil, false
+	for k := range e.Map {
+		delete(e.Map, k)
+	}
 	e.Buf.Reset()
 	e.logger.entryPool.Put(e)
 }
 
 type TextFormatter struct {
 	IgnoreBasicFields bool
+	// Color wraps the level in ANSI colors, for terminals.
+	Color bool
 }
 
 func (f *TextFormatter) Format(e *Entry) error {
 	if !f.IgnoreBasicFields {
-		e.Buf.WriteString(fmt.Sprintf("%s %s", e.Time.Format(time.RFC3339), LevelMapping[e.Level])) // allocs
+		lvl := e.levelName()
+		if f.Color {
+			lvl = colorize(e.Level, lvl)
+		}
+		e.Buf.WriteString(fmt.Sprintf("%s %s", e.Time.Format(time.RFC3339), lvl)) // allocs
 		if e.File != "" {
 			short := e.File
 			for i := len(e.File) - 1; i > 0; i-- {
@@ -301,6 +599,9 @@ func (f *TextFormatter) Format(e *Entry) error {
 	default:
 		e.Buf.WriteString(fmt.Sprintf(e.Format, e.Args...))
 	}
+	for _, k := range e.Fields.keys() {
+		writeTextField(e.Buf, k, e.Fields[k])
+	}
 	e.Buf.WriteString("\n")
 
 	return nil
@@ -308,14 +609,27 @@ func (f *TextFormatter) Format(e *Entry) error {
 
 type JSONFormatter struct {
 	IgnoreBasicFields bool
+	// ReservedKeys decides what happens to user fields named like one of
+	// the basic fields, ReservedPrefix is used when it is unset.
+	ReservedKeys ReservedPolicy
+	// FieldPrefix is prepended to colliding user keys under
+	// ReservedPrefix, defaults to "fields.".
+	FieldPrefix string
+	// TimeLayout formats time.Time values, RFC 3339 with nanoseconds
+	// when empty.
+	TimeLayout string
+	Durations  DurationEncoding
+	Bytes      BytesEncoding
+	Nils       NilEncoding
 }
 
 func (f *JSONFormatter) Format(e *Entry) error {
 	if !f.IgnoreBasicFields {
+		e.Map["schema_version"] = SchemaVersion
 		e.Map["level"] = LevelMapping[e.Level]
 		e.Map["time"] = e.Time.Format(time.RFC3339)
 		if e.File != "" {
-			e.Map["file"] = e.File + ":" + strconv.Itoa(e.Line)
+			e.Map["file"] = callSiteString(e.File, e.Line)
 			e.Map["func"] = e.Func
 		}
 
@@ -325,14 +639,25 @@ func (f *JSONFormatter) Format(e *Entry) error {
 		default:
 			e.Map["message"] = fmt.Sprintf(e.Format, e.Args...)
 		}
+		if msgs := argErrors(e.Args); msgs != nil {
+			e.Map["errors"] = msgs
+		}
 
-		return jsoniter.NewEncoder(e.Buf).Encode(e.Map)
+		merr := f.mergeFields(e)
+		if err := encodeJSON(e.Buf, e.Map); err != nil {
+			return err
+		}
+		return merr
 	}
 
 	switch e.Format {
 	case FmtEmptySeparate:
 		for _, arg := range e.Args {
-			if err := jsoniter.NewEncoder(e.Buf).Encode(arg); err != nil {
+			v, ok := f.value(jsonValue(arg))
+			if !ok {
+				continue
+			}
+			if err := encodeJSON(e.Buf, v); err != nil {
 				return err
 			}
 		}
@@ -344,7 +669,7 @@ func (f *JSONFormatter) Format(e *Entry) error {
 }
 
 func initOptions(opts ...Option) *options {
-	o := &options{}
+	o := &options{fatalCode: 1}
 	for _, opt := range opts {
 		opt(o)
 	}
@@ -383,9 +708,19 @@ func WithFormatter(fmt Formatter) Option {
 	}
 }
 
+// WithEnableCaller keeps its historical meaning: true stops recording the
+// file and function of the call site.
+//
+// Deprecated: use WithCaller, which is not inverted.
 func WithEnableCaller(caller bool) Option {
+	return WithCaller(!caller)
+}
+
+// WithCaller sets whether entries record the file and function of the
+// call site.
+func WithCaller(caller bool) Option {
 	return func(o *options) {
-		o.enableCaller = caller
+		o.disableCaller = !caller
 	}
 }
 
@@ -0,0 +1,26 @@
+package main
+
+// EntryHandler processes an entry after its fields and caller are set,
+// the innermost one formats, routes and writes it. Errors returned by a
+// middleware are reported through OnError.
+type EntryHandler func(e *Entry) error
+
+// WithMiddleware wraps the format and write pipeline with mw, so
+// enrichment, filtering, routing or metrics compose like HTTP middleware:
+// mw may change the entry, skip next to drop it or call it and observe
+// the result. The first middleware registered runs first.
+func WithMiddleware(mw func(next EntryHandler) EntryHandler) Option {
+	return func(o *options) {
+		o.middleware = append(o.middleware, mw)
+		var h EntryHandler = processEntry
+		for i := len(o.middleware) - 1; i >= 0; i-- {
+			h = o.middleware[i](h)
+		}
+		o.handler = h
+	}
+}
+
+func processEntry(e *Entry) error {
+	e.process()
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+var (
+	errMmapUnsupported = errors.New("logie: mmap writer not supported on this platform")
+	errMmapTooLarge    = errors.New("logie: entry larger than mmap segment")
+)
+
+const (
+	mmapHeaderSize = 4
+	// mmapPadding flags a header whose length counts the bytes skipped to
+	// reach the next page
+	mmapPadding = 1 << 31
+)
+
+// mmapAlign rounds n up to the 4-byte alignment of records.
+func mmapAlign(n int) int {
+	return (n + 3) &^ 3
+}
+
+func mmapSegment(path string, seq int) string {
+	return fmt.Sprintf("%s.%06d.mmap", path, seq)
+}
+
+func mmapSegments(path string) ([]string, error) {
+	names, err := filepath.Glob(path + ".*.mmap")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// scanRecords walks the length-prefixed records of a segment and returns
+// the offset following the last complete one. Records start 4-byte
+// aligned, padding headers skip to the next page. A zero header marks the
+// end of the written part, a header running past the segment means the
+// process crashed mid-append.
+func scanRecords(data []byte, fn func(rec []byte) error) (int, error) {
+	off := 0
+	for off+mmapHeaderSize <= len(data) {
+		n := int(binary.LittleEndian.Uint32(data[off:]))
+		if n == 0 {
+			break
+		}
+		if n&mmapPadding != 0 {
+			skip := mmapHeaderSize + n&^mmapPadding
+			if off+skip > len(data) {
+				break
+			}
+			off += skip
+			continue
+		}
+		if off+mmapHeaderSize+n > len(data) {
+			break
+		}
+		if fn != nil {
+			if err := fn(data[off+mmapHeaderSize : off+mmapHeaderSize+n]); err != nil {
+				return off, err
+			}
+		}
+		off += mmapAlign(mmapHeaderSize + n)
+	}
+	if off > len(data) {
+		off = len(data)
+	}
+	return off, nil
+}
+
+// MmapRecords calls fn with every entry stored by a MmapWriter at path,
+// oldest first.
+func MmapRecords(path string, fn func(rec []byte) error) error {
+	names, err := mmapSegments(path)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if _, err := scanRecords(data, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
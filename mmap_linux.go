@@ -0,0 +1,190 @@
+//go:build linux
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// MmapWriter is an experimental writer appending entries to preallocated,
+// memory-mapped segment files path.NNNNNN.mmap. Every entry is stored
+// behind a length header written after its payload, so a crash never
+// leaves a header pointing at missing data, and the next open resumes
+// after the last complete entry. An entry that would straddle a page
+// starts on the next one, so an append dirties as few pages as possible.
+type MmapWriter struct {
+	mu      sync.Mutex
+	path    string
+	segSize int
+	seq     int
+	fd      *os.File
+	data    []byte
+	off     int
+	synced  int
+}
+
+// NewMmapWriter opens the writer, segSize is rounded up to the page size.
+func NewMmapWriter(path string, segSize int) (*MmapWriter, error) {
+	page := os.Getpagesize()
+	if segSize < page {
+		segSize = page
+	}
+	segSize = (segSize + page - 1) / page * page
+
+	w := &MmapWriter{path: path, segSize: segSize}
+	names, err := mmapSegments(path)
+	if err != nil {
+		return nil, err
+	}
+	w.seq = 1
+	if len(names) > 0 {
+		last := names[len(names)-1]
+		if _, err := fmt.Sscanf(last[len(path)+1:], "%d.mmap", &w.seq); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *MmapWriter) open() error {
+	fd, err := os.OpenFile(mmapSegment(w.path, w.seq), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := fd.Stat()
+	if err != nil {
+		_ = fd.Close()
+		return err
+	}
+	size := int(fi.Size())
+	if size < w.segSize {
+		if err := fd.Truncate(int64(w.segSize)); err != nil {
+			_ = fd.Close()
+			return err
+		}
+		size = w.segSize
+	}
+	data, err := syscall.Mmap(int(fd.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		_ = fd.Close()
+		return err
+	}
+	off, _ := scanRecords(data, nil)
+	// wipe a torn append so the header after the last entry reads zero
+	if off+mmapHeaderSize <= len(data) {
+		binary.LittleEndian.PutUint32(data[off:], 0)
+	}
+	w.fd, w.data, w.off, w.synced = fd, data, off, off
+	return nil
+}
+
+func (w *MmapWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.data == nil {
+		return 0, errFileClosed
+	}
+	// a zero length header marks the end of the segment
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	need := mmapAlign(mmapHeaderSize + len(p))
+	if need+mmapHeaderSize > w.segSize {
+		return 0, errMmapTooLarge
+	}
+	pad := w.padding(need)
+	if w.off+pad+need+mmapHeaderSize > len(w.data) {
+		if err := w.next(); err != nil {
+			return 0, err
+		}
+		pad = 0
+	}
+
+	start := w.off + pad
+	copy(w.data[start+mmapHeaderSize:], p)
+	binary.LittleEndian.PutUint32(w.data[start+need:], 0)
+	binary.LittleEndian.PutUint32(w.data[start:], uint32(len(p)))
+	if pad > 0 {
+		// published last, the record is complete once it is readable
+		binary.LittleEndian.PutUint32(w.data[w.off:], uint32(mmapPadding|(pad-mmapHeaderSize)))
+	}
+	w.off = start + need
+	return len(p), nil
+}
+
+// padding returns the bytes to skip so a record of need bytes does not
+// straddle a page, records larger than a page start on a fresh one.
+func (w *MmapWriter) padding(need int) int {
+	page := os.Getpagesize()
+	in := w.off % page
+	if in == 0 || in+need <= page {
+		return 0
+	}
+	return page - in
+}
+
+func (w *MmapWriter) next() error {
+	if err := w.unmap(); err != nil {
+		return err
+	}
+	w.seq++
+	return w.open()
+}
+
+// Sync flushes the pages touched since the last Sync to disk.
+func (w *MmapWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.data == nil {
+		return errFileClosed
+	}
+	return w.msync()
+}
+
+func (w *MmapWriter) msync() error {
+	page := os.Getpagesize()
+	start := w.synced / page * page
+	end := w.off + mmapHeaderSize
+	if end > len(w.data) {
+		end = len(w.data)
+	}
+	if end <= start {
+		return nil
+	}
+	region := w.data[start:end]
+	_, _, errno := syscall.Syscall(syscall.SYS_MSYNC, uintptr(unsafe.Pointer(&region[0])), uintptr(len(region)), syscall.MS_SYNC)
+	if errno != 0 {
+		return errno
+	}
+	w.synced = w.off
+	return nil
+}
+
+func (w *MmapWriter) unmap() error {
+	if err := w.msync(); err != nil {
+		return err
+	}
+	if err := syscall.Munmap(w.data); err != nil {
+		return err
+	}
+	w.data = nil
+	return w.fd.Close()
+}
+
+func (w *MmapWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.data == nil {
+		return nil
+	}
+	return w.unmap()
+}
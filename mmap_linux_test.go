@@ -0,0 +1,116 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func mmapRecords(t *testing.T, path string) []string {
+	t.Helper()
+	var got []string
+	if err := MmapRecords(path, func(rec []byte) error {
+		got = append(got, string(rec))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestMmapWriterReopen(t *testing.T) {
+	page := os.Getpagesize()
+	tests := []struct {
+		name   string
+		first  []string
+		second []string
+		want   []string
+	}{
+		{
+			name:   "resumes after the last entry",
+			first:  []string{"a\n", "bb\n"},
+			second: []string{"ccc\n"},
+			want:   []string{"a\n", "bb\n", "ccc\n"},
+		},
+		{
+			name:   "empty writes store nothing",
+			first:  []string{"a\n", "", "b\n"},
+			second: []string{"", "c\n"},
+			want:   []string{"a\n", "b\n", "c\n"},
+		},
+		{
+			name:   "entries straddling a page",
+			first:  []string{strings.Repeat("x", page/2), strings.Repeat("y", page/2)},
+			second: []string{strings.Repeat("z", page/2)},
+			want:   []string{strings.Repeat("x", page/2), strings.Repeat("y", page/2), strings.Repeat("z", page/2)},
+		},
+		{
+			name:   "segment rollover",
+			first:  []string{strings.Repeat("x", page-64), strings.Repeat("y", page-64)},
+			second: []string{strings.Repeat("z", page-64)},
+			want:   []string{strings.Repeat("x", page-64), strings.Repeat("y", page-64), strings.Repeat("z", page-64)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			for _, batch := range [][]string{tt.first, tt.second} {
+				w, err := NewMmapWriter(path, page)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, e := range batch {
+					if _, err := w.Write([]byte(e)); err != nil {
+						t.Fatalf("Write(%d bytes): %v", len(e), err)
+					}
+				}
+				if err := w.Close(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := mmapRecords(t, path); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("records = %d %q, want %d entries", len(got), got, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestMmapWriterPageAligned(t *testing.T) {
+	page := os.Getpagesize()
+	w, err := NewMmapWriter(filepath.Join(t.TempDir(), "app.log"), 4*page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	entry := []byte(strings.Repeat("x", page/3))
+	for i := 0; i < 10; i++ {
+		before := w.off
+		if _, err := w.Write(entry); err != nil {
+			t.Fatal(err)
+		}
+		start := w.off - mmapAlign(mmapHeaderSize+len(entry))
+		if start%4 != 0 {
+			t.Fatalf("entry %d starts at %d, not 4-byte aligned", i, start)
+		}
+		if start/page != (w.off-1)/page {
+			t.Fatalf("entry %d spans %d-%d across a page (written after %d)", i, start, w.off, before)
+		}
+	}
+}
+
+func TestMmapWriterTooLarge(t *testing.T) {
+	page := os.Getpagesize()
+	w, err := NewMmapWriter(filepath.Join(t.TempDir(), "app.log"), page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if _, err := w.Write(make([]byte, page)); err != errMmapTooLarge {
+		t.Errorf("Write() error = %v, want errMmapTooLarge", err)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package main
+
+type MmapWriter struct{}
+
+func NewMmapWriter(path string, segSize int) (*MmapWriter, error) {
+	return nil, errMmapUnsupported
+}
+
+func (w *MmapWriter) Write(p []byte) (int, error) {
+	return 0, errMmapUnsupported
+}
+
+func (w *MmapWriter) Sync() error {
+	return errMmapUnsupported
+}
+
+func (w *MmapWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+)
+
+// mqttBroker accepts MQTT connections and keeps the PUBLISH packets as
+// topic and payload, acknowledging QoS 1 ones.
+type mqttBroker struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	pubs  [][2]string
+	conns []net.Conn
+}
+
+func newMQTTBroker(t *testing.T) *mqttBroker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &mqttBroker{ln: ln}
+	t.Cleanup(func() { ln.Close(); b.drop() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			b.mu.Lock()
+			b.conns = append(b.conns, conn)
+			b.mu.Unlock()
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *mqttBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+	for {
+		typ, body, err := readMQTTPacket(r)
+		if err != nil {
+			return
+		}
+		switch typ & 0xf0 {
+		case mqttConnect:
+			writeMQTTPacket(w, mqttConnack, []byte{0, 0})
+		case mqttPublish:
+			n := int(binary.BigEndian.Uint16(body))
+			topic, rest := string(body[2:2+n]), body[2+n:]
+			if typ&0x06 != 0 {
+				writeMQTTPacket(w, mqttPuback, rest[:2])
+				rest = rest[2:]
+			}
+			b.mu.Lock()
+			b.pubs = append(b.pubs, [2]string{topic, string(rest)})
+			b.mu.Unlock()
+		}
+		if w.Flush() != nil {
+			return
+		}
+	}
+}
+
+func (b *mqttBroker) drop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, c := range b.conns {
+		c.Close()
+	}
+	b.conns = nil
+}
+
+func (b *mqttBroker) got() [][2]string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([][2]string(nil), b.pubs...)
+}
+
+func TestMQTTSink(t *testing.T) {
+	tests := []struct {
+		name      string
+		topic     string
+		opts      []MQTTOption
+		log       func(l *Logger)
+		wantTopic string
+	}{
+		{
+			name:      "qos 0",
+			topic:     "logs",
+			log:       func(l *Logger) { l.Info("hi") },
+			wantTopic: "logs",
+		},
+		{
+			name:      "qos clamped to 1",
+			topic:     "logs",
+			opts:      []MQTTOption{WithQoS(2)},
+			log:       func(l *Logger) { l.Info("hi") },
+			wantTopic: "logs",
+		},
+		{
+			name:      "level in the topic",
+			topic:     "logs/{level}",
+			log:       func(l *Logger) { l.Warn("hi") },
+			wantTopic: "logs/warn",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newMQTTBroker(t)
+			s, err := NewMQTTSink("mqtt://"+b.ln.Addr().String(), tt.topic, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			tt.log(New(WithPosition(s), WithFormatter(&TextFormatter{IgnoreBasicFields: true})))
+			waitFor(t, "publish", func() bool { return len(b.got()) == 1 })
+			if got := b.got()[0]; got != [2]string{tt.wantTopic, "hi\n"} {
+				t.Errorf("published %q, want %q on %s", got, "hi\n", tt.wantTopic)
+			}
+		})
+	}
+}
+
+func TestMQTTSinkHookTopic(t *testing.T) {
+	b := newMQTTBroker(t)
+	s, err := NewMQTTSink("mqtt://"+b.ln.Addr().String(), "devices/{device_id}/{logger}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	l := New(WithPosition(&lockedBuilder{}), WithFormatter(&TextFormatter{IgnoreBasicFields: true}),
+		WithRoutes(&Route{Name: "mqtt", Hook: s.Hook}))
+	l.Named("gps").WithFields(Fields{"device_id": "a/b#1"}).Info("fix")
+	waitFor(t, "publish", func() bool { return len(b.got()) == 1 })
+	if got := b.got()[0][0]; got != "devices/a_b_1/gps" {
+		t.Errorf("topic %q, want separators and wildcards replaced", got)
+	}
+}
+
+func TestMQTTSinkReconnects(t *testing.T) {
+	b := newMQTTBroker(t)
+	s, err := NewMQTTSink("mqtt://"+b.ln.Addr().String(), "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Healthy(); err != nil {
+		t.Fatalf("Healthy() = %v while connected", err)
+	}
+	b.drop()
+	waitFor(t, "lost connection", func() bool { return errors.Is(s.Healthy(), errNotConnected) })
+	if _, err := s.Write([]byte("again\n")); err != nil {
+		t.Fatalf("Write() after the broker dropped the connection: %v", err)
+	}
+	waitFor(t, "publish", func() bool { return len(b.got()) == 1 })
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// msgCache keeps the formatted output of constant entries: no fields, no
+// caller and a single string argument. The timestamp is the only varying
+// part, it is patched into a copy of the cached bytes.
+type msgCache struct {
+	mu      sync.RWMutex
+	size    int
+	entries map[msgKey]*msgTemplate
+}
+
+type msgKey struct {
+	formatter uintptr
+	lvl       Level
+	msg       string
+}
+
+type msgTemplate struct {
+	out     []byte
+	timeAt  int
+	timeLen int
+}
+
+// WithMessageCache caches up to size formatted constant entries, for hot
+// paths logging static strings. Only formatters stamping the time as
+// RFC 3339 benefit, others are formatted as usual.
+func WithMessageCache(size int) Option {
+	return func(o *options) {
+		o.msgCache = &msgCache{size: size, entries: make(map[msgKey]*msgTemplate, size)}
+	}
+}
+
+func (c *msgCache) key(e *Entry) (msgKey, bool) {
+	if e.Format != FmtEmptySeparate || len(e.Args) != 1 || len(e.Fields) > 0 || e.File != "" {
+		return msgKey{}, false
+	}
+	msg, ok := e.Args[0].(string)
+	if !ok {
+		return msgKey{}, false
+	}
+	f := reflect.ValueOf(e.logger.opt.formatter)
+	if f.Kind() != reflect.Ptr {
+		return msgKey{}, false
+	}
+	return msgKey{formatter: f.Pointer(), lvl: e.Level, msg: msg}, true
+}
+
+// format writes the cached output of e and reports whether it could.
+func (c *msgCache) format(e *Entry, key msgKey) bool {
+	c.mu.RLock()
+	tpl, ok := c.entries[key]
+	c.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	if tpl == nil {
+		// formatted before, without a patchable timestamp
+		return false
+	}
+	ts := e.Time.Format(time.RFC3339)
+	if len(ts) != tpl.timeLen {
+		return false
+	}
+	e.Buf.Write(tpl.out[:tpl.timeAt])
+	e.Buf.WriteString(ts)
+	e.Buf.Write(tpl.out[tpl.timeAt+tpl.timeLen:])
+	return true
+}
+
+func (c *msgCache) store(e *Entry, key msgKey) {
+	out := e.Buf.Bytes()
+	ts := []byte(e.Time.Format(time.RFC3339))
+	var tpl *msgTemplate
+	if at := bytes.Index(out, ts); at >= 0 && bytes.LastIndex(out, ts) == at {
+		tpl = &msgTemplate{out: append([]byte(nil), out...), timeAt: at, timeLen: len(ts)}
+	}
+
+	// msg may alias bytes of a zero-copy Write
+	key.msg = string([]byte(key.msg))
+	c.mu.Lock()
+	if len(c.entries) >= c.size {
+		c.entries = make(map[msgKey]*msgTemplate, c.size)
+	}
+	c.entries[key] = tpl
+	c.mu.Unlock()
+}
@@ -0,0 +1,52 @@
+package main
+
+type multiError interface {
+	Unwrap() []error
+}
+
+// errorMessages flattens err into the messages of its causes when it was
+// built by errors.Join or similar, it returns nil for plain errors.
+func errorMessages(err error) []string {
+	me, ok := err.(multiError)
+	if !ok {
+		return nil
+	}
+	var msgs []string
+	for _, e := range me.Unwrap() {
+		if e == nil {
+			continue
+		}
+		if sub := errorMessages(e); sub != nil {
+			msgs = append(msgs, sub...)
+			continue
+		}
+		msgs = append(msgs, e.Error())
+	}
+	return msgs
+}
+
+// jsonValue converts errors, which encode as empty objects by default, to
+// their message or to the list of messages of a multi-error, and Valuers
+// to their fields.
+func jsonValue(v any) any {
+	v = resolveValuer(v)
+	err, ok := v.(error)
+	if !ok || err == nil {
+		return v
+	}
+	if msgs := errorMessages(err); msgs != nil {
+		return msgs
+	}
+	return err.Error()
+}
+
+// argErrors collects the messages of multi-error arguments.
+func argErrors(args []any) []string {
+	var msgs []string
+	for _, arg := range args {
+		if err, ok := arg.(error); ok && err != nil {
+			msgs = append(msgs, errorMessages(err)...)
+		}
+	}
+	return msgs
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// natsServer speaks enough of the NATS text protocol for NATSSink: it
+// keeps the published messages and acknowledges those with a reply
+// subject with ack.
+type natsServer struct {
+	ln    net.Listener
+	ack   string
+	mu    sync.Mutex
+	msgs  []string
+	conns []net.Conn
+}
+
+func newNATSServer(t *testing.T, ack string) *natsServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &natsServer{ln: ln, ack: ack}
+	t.Cleanup(func() { ln.Close(); s.drop() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *natsServer) serve(conn net.Conn) {
+	defer conn.Close()
+	io.WriteString(conn, "INFO {\"server_id\":\"test\"}\r\n")
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		switch args[0] {
+		case "PING":
+			io.WriteString(conn, "PONG\r\n")
+		case "PUB":
+			size, _ := strconv.Atoi(args[len(args)-1])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.msgs = append(s.msgs, args[1]+" "+string(payload[:size]))
+			s.mu.Unlock()
+			if len(args) == 4 && s.ack != "" {
+				fmt.Fprintf(conn, "MSG %s 1 %d\r\n%s\r\n", args[2], len(s.ack), s.ack)
+			}
+		}
+	}
+}
+
+// drop closes the connections of the clients.
+func (s *natsServer) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+	s.conns = nil
+}
+
+func (s *natsServer) got() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.msgs...)
+}
+
+func TestNATSSink(t *testing.T) {
+	tests := []struct {
+		name    string
+		ack     string
+		opts    []NATSOption
+		wantErr string
+	}{
+		{"core", "", nil, ""},
+		{"jetstream", `{"stream":"logs","seq":1}`, []NATSOption{WithJetStream(time.Second)}, ""},
+		{"jetstream error", `{"error":{"description":"no stream"}}`, []NATSOption{WithJetStream(time.Second)}, "no stream"},
+		{"jetstream timeout", "", []NATSOption{WithJetStream(10 * time.Millisecond)}, errNATSAckTimeout.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newNATSServer(t, tt.ack)
+			s, err := NewNATSSink("nats://"+srv.ln.Addr().String(), "logs", tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			_, err = s.Write([]byte("hello\n"))
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Write() error = %v, want %q", err, tt.wantErr)
+			}
+			waitFor(t, "message", func() bool { return len(srv.got()) == 1 })
+			if got := srv.got()[0]; got != "logs hello\n" {
+				t.Errorf("published %q", got)
+			}
+		})
+	}
+}
+
+func TestNATSSinkHook(t *testing.T) {
+	srv := newNATSServer(t, "")
+	s, err := NewNATSSink("nats://"+srv.ln.Addr().String(), "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	l := New(WithPosition(io.Discard), WithFormatter(&TextFormatter{IgnoreBasicFields: true}),
+		WithRoutes(&Route{Name: "nats", Hook: s.Hook}))
+	l.Named("api").Named("db").Info("slow")
+	waitFor(t, "message", func() bool { return len(srv.got()) == 1 })
+	if got := srv.got()[0]; !strings.HasPrefix(got, "logs.api.db slow") {
+		t.Errorf("published %q, want the subject of the logger", got)
+	}
+}
+
+func TestNATSSinkReconnects(t *testing.T) {
+	srv := newNATSServer(t, "")
+	s, err := NewNATSSink("nats://"+srv.ln.Addr().String(), "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Healthy(); err != nil {
+		t.Fatalf("Healthy() = %v while connected", err)
+	}
+	srv.drop()
+	waitFor(t, "lost connection", func() bool { return errors.Is(s.Healthy(), errNotConnected) })
+	if _, err := s.Write([]byte("again\n")); err != nil {
+		t.Fatalf("Write() after the server dropped the connection: %v", err)
+	}
+	if err := s.Healthy(); err != nil {
+		t.Errorf("Healthy() = %v after reconnecting", err)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build linux
+
+package main
+
+import "syscall"
+
+var networkFSTypes = map[int64]string{
+	0x6969:     "nfs",
+	0x517b:     "smb",
+	0xfe534d42: "smb2",
+	0xff534d42: "cifs",
+}
+
+// networkFS reports the network file system holding path, if any.
+func networkFS(path string) (string, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return "", false
+	}
+	name, ok := networkFSTypes[int64(st.Type)]
+	return name, ok
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package main
+
+func networkFS(path string) (string, bool) {
+	return "", false
+}
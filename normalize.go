@@ -0,0 +1,130 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type KeyPolicy uint8
+
+const (
+	KeyAsIs KeyPolicy = iota
+	KeySnakeCase
+	KeyCamelCase
+	KeyLowerCase
+)
+
+type CollisionPolicy uint8
+
+const (
+	// CollisionLastWins keeps the value of the key sorting last among
+	// those normalizing to the same name.
+	CollisionLastWins CollisionPolicy = iota
+	// CollisionSuffix keeps every value, renaming later keys to name_2,
+	// name_3 and so on.
+	CollisionSuffix
+)
+
+type keyNormalizer struct {
+	policy    KeyPolicy
+	collision CollisionPolicy
+	cache     *interner
+}
+
+// WithKeyNormalizer rewrites user field keys according to policy before
+// they reach the formatter.
+func WithKeyNormalizer(policy KeyPolicy, collision CollisionPolicy) Option {
+	return func(o *options) {
+		o.normalizer = &keyNormalizer{
+			policy:    policy,
+			collision: collision,
+			cache:     &interner{strings: make(map[string]string)},
+		}
+	}
+}
+
+func (l *Logger) normalize(fields Fields) Fields {
+	n := l.opt.normalizer
+	if n == nil || n.policy == KeyAsIs || len(fields) == 0 {
+		return fields
+	}
+
+	out := make(Fields, len(fields))
+	for _, k := range fields.keys() {
+		key := n.cache.intern(k, n.key)
+		if _, dup := out[key]; dup && n.collision == CollisionSuffix {
+			for i := 2; ; i++ {
+				if _, dup := out[key+"_"+strconv.Itoa(i)]; !dup {
+					key += "_" + strconv.Itoa(i)
+					break
+				}
+			}
+		}
+		out[key] = fields[k]
+	}
+	return out
+}
+
+// key normalizes k, keys without any letter or digit are kept as they are.
+func (n *keyNormalizer) key(k string) string {
+	switch n.policy {
+	case KeyLowerCase:
+		return strings.ToLower(k)
+	case KeySnakeCase:
+		words := splitWords(k)
+		if len(words) == 0 {
+			return k
+		}
+		for i, w := range words {
+			words[i] = strings.ToLower(w)
+		}
+		return strings.Join(words, "_")
+	case KeyCamelCase:
+		words := splitWords(k)
+		if len(words) == 0 {
+			return k
+		}
+		for i, w := range words {
+			w = strings.ToLower(w)
+			if i > 0 {
+				r, size := utf8.DecodeRuneInString(w)
+				w = string(unicode.ToUpper(r)) + w[size:]
+			}
+			words[i] = w
+		}
+		return strings.Join(words, "")
+	}
+	return k
+}
+
+// splitWords splits k on separators and case changes, keeping acronyms
+// together: "HTTPServer_id" becomes HTTP, Server, id.
+func splitWords(k string) []string {
+	var (
+		words []string
+		cur   []rune
+	)
+	runes := []rune(k)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(cur) > 0 {
+				words, cur = append(words, string(cur)), nil
+			}
+			continue
+		}
+		if unicode.IsUpper(r) && len(cur) > 0 {
+			prev := cur[len(cur)-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(prev) || nextLower {
+				words, cur = append(words, string(cur)), nil
+			}
+		}
+		cur = append(cur, r)
+	}
+	if len(cur) > 0 {
+		words = append(words, string(cur))
+	}
+	return words
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeyNormalizer(t *testing.T) {
+	tests := []struct {
+		key                 string
+		snake, camel, lower string
+	}{
+		{"userID", "user_id", "userId", "userid"},
+		{"HTTPServer_id", "http_server_id", "httpServerId", "httpserver_id"},
+		{"request-id", "request_id", "requestId", "request-id"},
+		{"über_größe", "über_größe", "überGröße", "über_größe"},
+		{"émile_élan", "émile_élan", "émileÉlan", "émile_élan"},
+		{"count2", "count2", "count2", "count2"},
+		{"--", "--", "--", "--"},
+		{"_", "_", "_", "_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			for _, c := range []struct {
+				policy KeyPolicy
+				want   string
+			}{{KeySnakeCase, tt.snake}, {KeyCamelCase, tt.camel}, {KeyLowerCase, tt.lower}} {
+				n := &keyNormalizer{policy: c.policy}
+				if got := n.key(tt.key); got != c.want {
+					t.Errorf("policy %d: key(%q) = %q, want %q", c.policy, tt.key, got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeCollisions(t *testing.T) {
+	fields := Fields{"user_id": 1, "userId": 2, "UserID": 3}
+	tests := []struct {
+		name      string
+		collision CollisionPolicy
+		want      Fields
+	}{
+		{"last wins", CollisionLastWins, Fields{"user_id": 1}},
+		{"suffix", CollisionSuffix, Fields{"user_id": 3, "user_id_2": 2, "user_id_3": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(WithPosition(&lockedBuilder{}), WithKeyNormalizer(KeySnakeCase, tt.collision))
+			if got := l.normalize(fields); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
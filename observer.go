@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// WithClock replaces time.Now for entry timestamps, sampling windows and
+// the timing helpers, see FakeClock.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.clock = now
+	}
+}
+
+func (l *Logger) now() time.Time {
+	if l.opt.clock != nil {
+		return l.opt.clock()
+	}
+	return time.Now()
+}
+
+// FakeClock is a manually advanced clock for tests.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+type ObservedEntry struct {
+	Level   Level
+	Time    time.Time
+	Message string
+	Fields  Fields
+}
+
+// Observer records the entries of a logger for assertions in tests.
+type Observer struct {
+	mu      sync.Mutex
+	entries []ObservedEntry
+}
+
+// WithObserver records every entry written by the logger into obs.
+func WithObserver(obs *Observer) Option {
+	return func(o *options) {
+		o.observer = obs
+	}
+}
+
+// NewObserved returns a logger discarding its output and the observer
+// recording its entries, timestamps come from the clock set by opts.
+func NewObserved(opts ...Option) (*Logger, *Observer) {
+	obs := &Observer{}
+	return New(append([]Option{WithLevel(TraceLevel), WithPosition(io.Discard), WithObserver(obs)}, opts...)...), obs
+}
+
+func (e *Entry) observe() {
+	obs := e.logger.opt.observer
+	if obs == nil {
+		return
+	}
+	msg := fmt.Sprint(e.Args...)
+	if e.Format != FmtEmptySeparate {
+		msg = fmt.Sprintf(e.Format, e.Args...)
+	}
+	fields := make(Fields, len(e.Fields))
+	for k, v := range e.Fields {
+		fields[k] = v
+	}
+
+	obs.mu.Lock()
+	obs.entries = append(obs.entries, ObservedEntry{Level: e.Level, Time: e.Time, Message: msg, Fields: fields})
+	obs.mu.Unlock()
+}
+
+func (o *Observer) All() []ObservedEntry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]ObservedEntry(nil), o.entries...)
+}
+
+func (o *Observer) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.entries)
+}
+
+func (o *Observer) Reset() {
+	o.mu.Lock()
+	o.entries = nil
+	o.mu.Unlock()
+}
+
+func (o *Observer) FilterMessage(msg string) []ObservedEntry {
+	var out []ObservedEntry
+	for _, e := range o.All() {
+		if e.Message == msg {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// HappenedBefore reports whether the first entry with message a was
+// stamped strictly before the first entry with message b.
+func (o *Observer) HappenedBefore(a, b string) bool {
+	ea, eb := o.FilterMessage(a), o.FilterMessage(b)
+	return len(ea) > 0 && len(eb) > 0 && ea[0].Time.Before(eb[0].Time)
+}
+
+// WithinWindow returns the entries stamped in [from, to).
+func (o *Observer) WithinWindow(from, to time.Time) []ObservedEntry {
+	var out []ObservedEntry
+	for _, e := range o.All() {
+		if !e.Time.Before(from) && e.Time.Before(to) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
@@ -0,0 +1,72 @@
+package main
+
+import "time"
+
+// Operation is a named unit of work started by Begin. Entries logged
+// through its Logger carry op_id, op_parent_id and op_depth, and
+// operations begun from that logger become its children, so the entries
+// of a job form a tree without tracing infrastructure.
+type Operation struct {
+	logger *Logger
+	name   string
+	start  time.Time
+}
+
+// Begin starts the operation name, logging its start at DebugLevel.
+func (l *Logger) Begin(name string, fields ...Fields) *Operation {
+	op := l.begin(name, fields)
+	if op.logger.enabled(DebugLevel) {
+		op.logger.entry().write(DebugLevel, FmtEmptySeparate, "begin "+name)
+	}
+	return op
+}
+
+func (l *Logger) begin(name string, fields []Fields) *Operation {
+	opFields := Fields{"op": name, "op_id": newID(), "op_depth": 0}
+	if parent, ok := l.fields["op_id"]; ok {
+		opFields["op_parent_id"] = parent
+		if depth, ok := l.fields["op_depth"].(int); ok {
+			opFields["op_depth"] = depth + 1
+		}
+	}
+	for _, f := range fields {
+		for k, v := range f {
+			opFields[k] = v
+		}
+	}
+	return &Operation{logger: l.WithFields(opFields), name: name, start: l.now()}
+}
+
+// Begin starts a child operation of op.
+func (op *Operation) Begin(name string, fields ...Fields) *Operation {
+	child := op.logger.begin(name, fields)
+	if child.logger.enabled(DebugLevel) {
+		child.logger.entry().write(DebugLevel, FmtEmptySeparate, "begin "+name)
+	}
+	return child
+}
+
+// Logger returns the logger tagging entries with the operation fields.
+func (op *Operation) Logger() *Logger {
+	return op.logger
+}
+
+func (op *Operation) ID() string {
+	id, _ := op.logger.fields["op_id"].(string)
+	return id
+}
+
+// End logs the completion of op with its latency, see Latency, at
+// InfoLevel or at ErrorLevel with the error when err is not nil.
+func (op *Operation) End(err error) {
+	fields := Latency(op.start, op.logger.now())
+	fields["status"] = "ok"
+	lvl := InfoLevel
+	if err != nil {
+		fields["status"], fields["error"] = "error", err.Error()
+		lvl = ErrorLevel
+	}
+	if op.logger.enabled(lvl) {
+		op.logger.WithFields(fields).entry().write(lvl, FmtEmptySeparate, "end "+op.name)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOperationEnd(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "ok", want: "end sync latency=2s latency_ms=2000 op=sync op_depth=0 op_id=%s status=ok\n"},
+		{name: "error", err: errors.New("boom"), want: "end sync error=boom latency=2s latency_ms=2000 op=sync op_depth=0 op_id=%s status=error\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &collector{}
+			now := time.Unix(0, 0)
+			l := New(WithPosition(out), WithFormatter(&TextFormatter{IgnoreBasicFields: true}),
+				WithLevel(InfoLevel), WithClock(func() time.Time { return now }))
+			op := l.Begin("sync")
+			now = now.Add(2 * time.Second)
+			op.End(tt.err)
+			got := out.got()
+			if want := fmt.Sprintf(tt.want, op.ID()); len(got) != 1 || got[0] != want {
+				t.Errorf("logged %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestOperationTree(t *testing.T) {
+	out := &collector{}
+	l := New(WithPosition(out), WithFormatter(&TextFormatter{IgnoreBasicFields: true}), WithLevel(InfoLevel))
+	parent := l.Begin("job")
+	child := parent.Begin("step")
+	child.Logger().Info("working")
+
+	got := out.got()
+	if len(got) != 1 {
+		t.Fatalf("logged %q", got)
+	}
+	for _, want := range []string{"op=step", "op_depth=1", "op_parent_id=" + parent.ID(), "op_id=" + child.ID()} {
+		if !strings.Contains(got[0], want) {
+			t.Errorf("entry %q lacks %q", got[0], want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// WithOutputFunc picks the output of every entry at write time, for
+// instance stderr for Fatal only. A nil result falls back to the
+// position set by WithPosition.
+func WithOutputFunc(fn func(lvl Level) io.Writer) Option {
+	return func(o *options) {
+		o.outputFunc = fn
+	}
+}
+
+func (l *Logger) destination(lvl Level) io.Writer {
+	if fn := l.opt.outputFunc; fn != nil {
+		if w := fn(lvl); w != nil {
+			return w
+		}
+	}
+	return l.opt.position
+}
+
+// WithStdSplit sends Info and lower entries to stdout and Warn and higher
+// to stderr, the convention of twelve-factor apps and Kubernetes.
+func WithStdSplit() Option {
+	return WithOutputFunc(func(lvl Level) io.Writer {
+		if lvl >= WarnLevel {
+			return os.Stderr
+		}
+		return os.Stdout
+	})
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// Field packs emit the same key names in every service, combine them
+// with Merge.
+
+// HTTPFields returns the fields describing r, HTTPRequest without
+// headers.
+func HTTPFields(r *http.Request) Fields {
+	return HTTPRequest(r)
+}
+
+// DBFields describes a database call: its statement, truncated, the
+// number of rows and the duration.
+func DBFields(query string, rows int64, dur time.Duration) Fields {
+	return Fields{
+		"db.statement":   truncate(query, httpValueLimit),
+		"db.rows":        rows,
+		"db.duration":    dur.String(),
+		"db.duration_ms": float64(dur) / float64(time.Millisecond),
+	}
+}
+
+// UserFields describes the user behind an entry, role is left out when
+// empty.
+func UserFields(id, role string) Fields {
+	fields := Fields{"user.id": id}
+	if role != "" {
+		fields["user.role"] = role
+	}
+	return fields
+}
+
+// Merge returns a new map holding all packs, later ones winning on
+// duplicate keys.
+func Merge(packs ...Fields) Fields {
+	var merged Fields
+	for _, p := range packs {
+		merged = mergeFields(merged, p)
+	}
+	return merged
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is reported through OnError when a formatter or a route
+// transform or hook panics, the entry is still written when possible.
+type PanicError struct {
+	Op    string
+	Value any
+	Stack []byte
+}
+
+func newPanicError(op string, v any) *PanicError {
+	return &PanicError{Op: op, Value: v, Stack: debug.Stack()}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("logie: %s panicked: %v", e.Op, e.Value)
+}
+
+// PanicValue describes a value returned by recover as fields: panic=true
+// for alert queries, the rendered value, its type and the stack. Call it
+// from the deferred function so the stack still holds the panicking
+// frames.
+func PanicValue(v any) Fields {
+	var rendered string
+	switch val := v.(type) {
+	case error:
+		rendered = val.Error()
+	case string:
+		rendered = val
+	case fmt.Stringer:
+		rendered = val.String()
+	default:
+		rendered = fmt.Sprintf("%+v", val)
+	}
+	return Fields{
+		"panic":       true,
+		"panic_value": rendered,
+		"panic_type":  fmt.Sprintf("%T", v),
+		"stack":       string(debug.Stack()),
+	}
+}
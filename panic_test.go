@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type stringer struct{}
+
+func (stringer) String() string { return "stringer" }
+
+func TestPanicValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		v         any
+		wantValue string
+		wantType  string
+	}{
+		{"error", errors.New("boom"), "boom", "*errors.errorString"},
+		{"string", "boom", "boom", "string"},
+		{"stringer", stringer{}, "stringer", "main.stringer"},
+		{"struct", struct{ N int }{7}, "{N:7}", "struct { N int }"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := PanicValue(tt.v)
+			if f["panic"] != true || f["panic_value"] != tt.wantValue || f["panic_type"] != tt.wantType {
+				t.Errorf("PanicValue() = %v, want value %q of type %s", f, tt.wantValue, tt.wantType)
+			}
+			if !strings.Contains(f["stack"].(string), "TestPanicValue") {
+				t.Errorf("stack does not hold the caller")
+			}
+		})
+	}
+}
+
+// panicKeys checks the panic keys shared by every entry about a panic.
+func panicKeys(t *testing.T, line []byte, wantValue string) {
+	t.Helper()
+	var m map[string]any
+	if err := decodeJSON(line, &m); err != nil {
+		t.Fatalf("decode %s: %v", line, err)
+	}
+	if m["panic"] != true || m["panic_value"] != wantValue {
+		t.Errorf("entry %s, want panic=true and panic_value=%q", line, wantValue)
+	}
+}
+
+func TestReportCrashPanicKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crash.log")
+	if err := os.WriteFile(path, []byte("panic: boom\n\ngoroutine 1 [running]:\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	l := New(WithPosition(&buf), WithFormatter(&JSONFormatter{}))
+	if found, err := l.ReportCrash(path); !found || err != nil {
+		t.Fatalf("ReportCrash() = %v, %v", found, err)
+	}
+	panicKeys(t, bytes.TrimSpace(buf.Bytes()), "panic: boom")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Partition selects the partition key fields of WithPartitionFields.
+type Partition uint8
+
+const (
+	// PartitionDate adds date=2006-01-02.
+	PartitionDate Partition = 1 << iota
+	// PartitionHour adds hour=15.
+	PartitionHour
+	// PartitionWeek adds the ISO week, week=2006-W01.
+	PartitionWeek
+)
+
+type partitioner struct {
+	parts  Partition
+	cached atomic.Value // *partitionFields
+}
+
+type partitionFields struct {
+	start, end time.Time
+	fields     Fields
+}
+
+// WithPartitionFields stamps entries with the selected partition keys of
+// their UTC time, for Hive style partitioning downstream without parsing
+// timestamps. The keys are computed once per hour.
+func WithPartitionFields(parts Partition) Option {
+	return func(o *options) {
+		o.partition = &partitioner{parts: parts}
+	}
+}
+
+func (e *Entry) addPartition() {
+	p := e.logger.opt.partition
+	if p == nil {
+		return
+	}
+	t := e.Time.UTC()
+	c, _ := p.cached.Load().(*partitionFields)
+	if c == nil || t.Before(c.start) || !t.Before(c.end) {
+		c = p.compute(t)
+		p.cached.Store(c)
+	}
+	e.Fields = mergeFields(e.Fields, c.fields)
+}
+
+func (p *partitioner) compute(t time.Time) *partitionFields {
+	start := t.Truncate(time.Hour)
+	fields := Fields{}
+	if p.parts&PartitionDate != 0 {
+		fields["date"] = start.Format("2006-01-02")
+	}
+	if p.parts&PartitionHour != 0 {
+		fields["hour"] = start.Format("15")
+	}
+	if p.parts&PartitionWeek != 0 {
+		year, week := start.ISOWeek()
+		fields["week"] = fmt.Sprintf("%d-W%02d", year, week)
+	}
+	return &partitionFields{start: start, end: start.Add(time.Hour), fields: fields}
+}
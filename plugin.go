@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// Heavyweight sinks and formatters, such as Kafka or CloudWatch, stay out
+// of the core module in one of two ways. Files of this package behind a
+// build tag register them from init with RegisterSink or
+// RegisterFormatter, only builds selecting the tag pay for their
+// dependencies. Or a Go plugin built with -buildmode=plugin exports them
+// with standard types only, see LoadPlugin:
+//
+//	var LogieSinks = map[string]func(u *url.URL) (io.Writer, error){...}
+//	var LogieFormatters = map[string]func(cfg map[string]any) func(record map[string]any) ([]byte, error){...}
+
+// PluginSinks is the type of the LogieSinks symbol of a plugin.
+type PluginSinks = map[string]func(u *url.URL) (io.Writer, error)
+
+// PluginFormatters is the type of the LogieFormatters symbol of a plugin.
+// The record holds time, level, message, the caller when known and the
+// fields of the entry.
+type PluginFormatters = map[string]func(cfg map[string]any) func(record map[string]any) ([]byte, error)
+
+func registerPlugin(path string, sinks PluginSinks, formatters PluginFormatters) error {
+	if sinks == nil && formatters == nil {
+		return fmt.Errorf("logie: plugin %s exports neither LogieSinks nor LogieFormatters", path)
+	}
+	for scheme, factory := range sinks {
+		RegisterSink(scheme, SinkFactory(factory))
+	}
+	for name, factory := range formatters {
+		factory := factory
+		RegisterFormatter(name, func(cfg map[string]any) Formatter {
+			return pluginFormatter(factory(cfg))
+		})
+	}
+	return nil
+}
+
+type pluginFormatter func(record map[string]any) ([]byte, error)
+
+func (f pluginFormatter) Format(e *Entry) error {
+	record := map[string]any{
+		"time":    e.Time,
+		"level":   LevelMapping[e.Level],
+		"message": entryMessage(e),
+		"fields":  map[string]any(e.Fields),
+	}
+	if e.File != "" {
+		record["file"] = callSiteString(e.File, e.Line)
+		record["func"] = e.Func
+	}
+	out, err := f(record)
+	if err != nil {
+		return err
+	}
+	e.Buf.Write(out)
+	return nil
+}
+
+func entryMessage(e *Entry) string {
+	if e.Format != FmtEmptySeparate {
+		return fmt.Sprintf(e.Format, e.Args...)
+	}
+	return fmt.Sprint(e.Args...)
+}
@@ -0,0 +1,11 @@
+//go:build !cgo || !(linux || darwin || freebsd)
+
+package main
+
+import "errors"
+
+// LoadPlugin needs cgo on Linux, macOS or FreeBSD, register sinks from
+// build tagged files instead.
+func LoadPlugin(path string) error {
+	return errors.New("logie: go plugins are not supported on this platform")
+}
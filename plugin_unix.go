@@ -0,0 +1,35 @@
+//go:build cgo && (linux || darwin || freebsd)
+
+package main
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// LoadPlugin opens the Go plugin at path and registers the sinks and
+// formatters it exports, see PluginSinks and PluginFormatters. The plugin
+// must be built by the same toolchain as the program.
+func LoadPlugin(path string) error {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return fmt.Errorf("logie: %w", err)
+	}
+	var sinks PluginSinks
+	var formatters PluginFormatters
+	if sym, err := p.Lookup("LogieSinks"); err == nil {
+		s, ok := sym.(*PluginSinks)
+		if !ok {
+			return fmt.Errorf("logie: plugin %s: LogieSinks has type %T", path, sym)
+		}
+		sinks = *s
+	}
+	if sym, err := p.Lookup("LogieFormatters"); err == nil {
+		f, ok := sym.(*PluginFormatters)
+		if !ok {
+			return fmt.Errorf("logie: plugin %s: LogieFormatters has type %T", path, sym)
+		}
+		formatters = *f
+	}
+	return registerPlugin(path, sinks, formatters)
+}
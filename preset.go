@@ -0,0 +1,61 @@
+package main
+
+import (
+	"runtime/debug"
+	"time"
+)
+
+var levelColors = map[Level]string{
+	TraceLevel: "\x1b[90m",
+	DebugLevel: "\x1b[90m",
+	InfoLevel:  "\x1b[36m",
+	WarnLevel:  "\x1b[33m",
+	ErrorLevel: "\x1b[31m",
+	PanicLevel: "\x1b[35m",
+	FatalLevel: "\x1b[35m",
+}
+
+func colorize(lvl Level, s string) string {
+	return levelColors[lvl] + s + "\x1b[0m"
+}
+
+// WithStacktrace adds the goroutine stack as the stack field of entries
+// at lvl or above.
+func WithStacktrace(lvl Level) Option {
+	return func(o *options) {
+		o.stacktrace, o.stackLevel = true, lvl
+	}
+}
+
+func withStack(fields Fields) Fields {
+	out := make(Fields, len(fields)+1)
+	for k, v := range fields {
+		out[k] = v
+	}
+	out["stack"] = string(debug.Stack())
+	return out
+}
+
+// NewDevelopment returns a logger for local development: colored text at
+// Debug level with callers and stacks on errors. opts are applied last.
+func NewDevelopment(opts ...Option) *Logger {
+	return New(append([]Option{
+		WithLevel(DebugLevel),
+		WithFormatter(&TextFormatter{Color: true}),
+		WithCaller(true),
+		WithStacktrace(ErrorLevel),
+	}, opts...)...)
+}
+
+// NewProduction returns a logger for services: sampled, asynchronous JSON
+// at Info level without callers. opts are applied last.
+func NewProduction(opts ...Option) *Logger {
+	return New(append([]Option{
+		WithLevel(InfoLevel),
+		WithFormatter(&JSONFormatter{}),
+		WithCaller(false),
+		WithSampling(100, 100, time.Second),
+		WithAsync(4096),
+		func(o *options) { o.production = true },
+	}, opts...)...)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Progress reports the advance of a long operation, logging at most one
+// entry per interval and always a final summary from Done.
+type Progress struct {
+	mu       sync.Mutex
+	logger   *Logger
+	msg      string
+	total    int64
+	count    int64
+	interval time.Duration
+	start    time.Time
+	last     time.Time
+}
+
+// Progress tracks an operation of total units, total may be zero when it
+// is unknown.
+func (l *Logger) Progress(msg string, total int64, interval time.Duration) *Progress {
+	now := l.now()
+	return &Progress{logger: l, msg: msg, total: total, interval: interval, start: now, last: now}
+}
+
+func (p *Progress) Add(n int64) {
+	p.mu.Lock()
+	p.count += n
+	now := p.logger.now()
+	if now.Sub(p.last) < p.interval {
+		p.mu.Unlock()
+		return
+	}
+	p.last = now
+	fields := p.fields(now)
+	p.mu.Unlock()
+
+	p.logger.WithFields(fields).entry().write(InfoLevel, FmtEmptySeparate, p.msg)
+}
+
+func (p *Progress) Done() {
+	p.mu.Lock()
+	fields := p.fields(p.logger.now())
+	p.mu.Unlock()
+	fields["status"] = "done"
+
+	p.logger.WithFields(fields).entry().write(InfoLevel, FmtEmptySeparate, p.msg)
+}
+
+func (p *Progress) fields(now time.Time) Fields {
+	elapsed := now.Sub(p.start)
+	fields := Fields{
+		"processed": p.count,
+		"elapsed":   elapsed.Round(time.Millisecond).String(),
+	}
+	if secs := elapsed.Seconds(); secs > 0 {
+		fields["rate"] = float64(p.count) / secs
+	}
+	if p.total > 0 {
+		fields["total"] = p.total
+		fields["percent"] = float64(p.count) * 100 / float64(p.total)
+		if p.count > 0 && p.count < p.total {
+			eta := time.Duration(float64(elapsed) * float64(p.total-p.count) / float64(p.count))
+			fields["eta"] = eta.Round(time.Second).String()
+		}
+	}
+	return fields
+}
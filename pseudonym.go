@@ -0,0 +1,64 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+type pseudonymizer struct {
+	fields map[string]struct{}
+	salt   []byte
+}
+
+// WithPseudonymize replaces the values of the named fields with a salted
+// hash, entries stay correlatable without exposing the original value.
+func WithPseudonymize(fields []string, salt string) Option {
+	p := &pseudonymizer{fields: make(map[string]struct{}, len(fields)), salt: []byte(salt)}
+	for _, f := range fields {
+		p.fields[f] = struct{}{}
+	}
+	return func(o *options) {
+		o.pseudonymizer = p
+	}
+}
+
+func (p *pseudonymizer) hash(v any) string {
+	mac := hmac.New(sha256.New, p.salt)
+	_, _ = fmt.Fprint(mac, v)
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// apply returns fields with configured values hashed, fields itself is
+// never modified.
+func (p *pseudonymizer) apply(fields Fields) Fields {
+	var out Fields
+	for k, v := range fields {
+		if _, ok := p.fields[k]; !ok || v == nil {
+			continue
+		}
+		if out == nil {
+			out = make(Fields, len(fields))
+			for k, v := range fields {
+				out[k] = v
+			}
+		}
+		out[k] = p.hash(v)
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+// prepareFields applies the privacy options to the fields of an entry.
+func (l *Logger) prepareFields(fields Fields) Fields {
+	if p := l.opt.pseudonymizer; p != nil {
+		fields = p.apply(fields)
+	}
+	if len(l.opt.anonymizeIP) > 0 {
+		fields = anonymizeIPs(fields, l.opt.anonymizeIP)
+	}
+	return fields
+}
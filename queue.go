@@ -0,0 +1,451 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errQueueFull = errors.New("logie: disk queue is full")
+
+const (
+	segmentExt = ".seg"
+	cursorFile = "cursor"
+	// a record is its length and CRC-32 followed by the entry
+	recordHeaderSize  = 8
+	maxRecordSize     = 64 << 20
+	queueRetryBackoff = time.Second
+	queueRetryMax     = 30 * time.Second
+	// cursorInterval bounds how often the read position is persisted while
+	// entries keep flowing
+	cursorInterval = time.Second
+)
+
+type QueueOption func(*DiskQueue)
+
+// DiskQueue is a write-ahead queue placed in front of a network output.
+// Entries are appended to segment files under dir and forwarded to out by
+// a background goroutine. The read position is persisted once the queue
+// is drained and at most every second while it is not, so delivery
+// resumes after a restart (at least once).
+type DiskQueue struct {
+	mu       sync.Mutex
+	dir      string
+	out      io.Writer
+	segSize  int64
+	maxBytes int64
+	used     int64
+	wseq     uint64
+	wfd      *os.File
+	wsize    int64
+	closed   bool
+	notify   chan struct{}
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func WithSegmentSize(size int64) QueueOption {
+	return func(q *DiskQueue) {
+		q.segSize = size
+	}
+}
+
+// WithMaxDiskUsage rejects new entries once the queued segments take up
+// more than size bytes.
+func WithMaxDiskUsage(size int64) QueueOption {
+	return func(q *DiskQueue) {
+		q.maxBytes = size
+	}
+}
+
+func NewDiskQueue(dir string, out io.Writer, opts ...QueueOption) (*DiskQueue, error) {
+	q := &DiskQueue{
+		dir:     dir,
+		out:     out,
+		segSize: 16 << 20,
+		notify:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	seqs, err := q.segments()
+	if err != nil {
+		return nil, err
+	}
+	for _, seq := range seqs {
+		if fi, err := os.Stat(q.segment(seq)); err == nil {
+			q.used += fi.Size()
+		}
+	}
+	if len(seqs) == 0 {
+		seqs = []uint64{1}
+	}
+	q.wseq = seqs[len(seqs)-1]
+	if err := q.repair(q.segment(q.wseq)); err != nil {
+		return nil, err
+	}
+	if err := q.openSegment(); err != nil {
+		return nil, err
+	}
+
+	rseq, roff := q.loadCursor()
+	if rseq < seqs[0] {
+		rseq, roff = seqs[0], 0
+	} else if rseq > q.wseq || (rseq == q.wseq && roff > q.wsize) {
+		rseq, roff = q.wseq, 0
+	}
+	q.wg.Add(1)
+	go q.run(rseq, roff)
+	return q, nil
+}
+
+func (q *DiskQueue) segment(seq uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+}
+
+func (q *DiskQueue) segments() ([]uint64, error) {
+	names, err := filepath.Glob(filepath.Join(q.dir, "*"+segmentExt))
+	if err != nil {
+		return nil, err
+	}
+	seqs := make([]uint64, 0, len(names))
+	for _, name := range names {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(name), segmentExt), 10, 64)
+		if err == nil {
+			seqs = append(seqs, seq)
+		}
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	return seqs, nil
+}
+
+// repair truncates the torn tail left in the segment at path by a crash
+// during a write.
+func (q *DiskQueue) repair(path string) error {
+	fd, err := os.OpenFile(path, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer fd.Close()
+	fi, err := fd.Stat()
+	if err != nil {
+		return err
+	}
+	var off int64
+	for off < fi.Size() {
+		rec, err := readRecord(fd, off, fi.Size())
+		if err != nil {
+			break
+		}
+		off += recordHeaderSize + int64(len(rec))
+	}
+	if off == fi.Size() {
+		return nil
+	}
+	q.used -= fi.Size() - off
+	return fd.Truncate(off)
+}
+
+var errBadRecord = errors.New("logie: corrupt disk queue record")
+
+// readRecord reads the record at off of a segment of size bytes, checking
+// its length and checksum.
+func readRecord(r io.ReaderAt, off, size int64) ([]byte, error) {
+	var hdr [recordHeaderSize]byte
+	if _, err := r.ReadAt(hdr[:], off); err != nil {
+		return nil, err
+	}
+	n := int64(binary.BigEndian.Uint32(hdr[:4]))
+	if n > maxRecordSize || off+recordHeaderSize+n > size {
+		return nil, errBadRecord
+	}
+	rec := make([]byte, n)
+	if _, err := r.ReadAt(rec, off+recordHeaderSize); err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(rec) != binary.BigEndian.Uint32(hdr[4:]) {
+		return nil, errBadRecord
+	}
+	return rec, nil
+}
+
+// newRecord frames p with its length and checksum, see readRecord.
+func newRecord(p []byte) []byte {
+	rec := make([]byte, recordHeaderSize+len(p))
+	binary.BigEndian.PutUint32(rec, uint32(len(p)))
+	binary.BigEndian.PutUint32(rec[4:], crc32.ChecksumIEEE(p))
+	copy(rec[recordHeaderSize:], p)
+	return rec
+}
+
+func (q *DiskQueue) openSegment() error {
+	fd, err := os.OpenFile(q.segment(q.wseq), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := fd.Stat()
+	if err != nil {
+		_ = fd.Close()
+		return err
+	}
+	q.wfd, q.wsize = fd, fi.Size()
+	return nil
+}
+
+func (q *DiskQueue) Write(p []byte) (int, error) {
+	if len(p) > maxRecordSize {
+		return 0, fmt.Errorf("logie: entry of %d bytes exceeds the disk queue record limit", len(p))
+	}
+	size := int64(recordHeaderSize + len(p))
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return 0, errFileClosed
+	}
+	if q.wfd == nil {
+		// a previous rollover or rollback failed
+		if err := q.openSegment(); err != nil {
+			return 0, err
+		}
+	}
+	if q.maxBytes > 0 && q.used+size > q.maxBytes {
+		return 0, errQueueFull
+	}
+	if q.wsize > 0 && q.wsize+size > q.segSize {
+		err := q.wfd.Close()
+		q.wseq, q.wfd, q.wsize = q.wseq+1, nil, 0
+		if err != nil {
+			return 0, err
+		}
+		if err := q.openSegment(); err != nil {
+			return 0, err
+		}
+	}
+
+	rec := newRecord(p)
+	n, err := q.wfd.Write(rec)
+	if err == nil && n < len(rec) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		// roll back the partial record so later ones stay framed
+		if n > 0 && q.wfd.Truncate(q.wsize) != nil {
+			_ = q.wfd.Close()
+			q.wfd = nil
+		}
+		return 0, err
+	}
+	q.wsize += int64(n)
+	q.used += int64(n)
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+func (q *DiskQueue) run(rseq uint64, roff int64) {
+	defer q.wg.Done()
+
+	var (
+		rfd   *os.File
+		dirty bool
+		saved = time.Now()
+	)
+	save := func() {
+		q.saveCursor(rseq, roff)
+		dirty, saved = false, time.Now()
+	}
+	defer func() {
+		if rfd != nil {
+			_ = rfd.Close()
+		}
+		if dirty {
+			save()
+		}
+	}()
+
+	for {
+		select {
+		case <-q.done:
+			return
+		default:
+		}
+		q.mu.Lock()
+		wseq, limit := q.wseq, q.wsize
+		q.mu.Unlock()
+		if rseq != wseq {
+			fi, err := os.Stat(q.segment(rseq))
+			if err != nil {
+				rseq, roff = rseq+1, 0
+				continue
+			}
+			limit = fi.Size()
+		}
+
+		if roff >= limit {
+			if rseq == wseq {
+				if dirty {
+					save()
+				}
+				select {
+				case <-q.notify:
+					continue
+				case <-q.done:
+					return
+				}
+			}
+			if rfd != nil {
+				_ = rfd.Close()
+				rfd = nil
+			}
+			if os.Remove(q.segment(rseq)) == nil {
+				q.mu.Lock()
+				q.used -= limit
+				q.mu.Unlock()
+			}
+			rseq, roff = rseq+1, 0
+			save()
+			continue
+		}
+
+		if rfd == nil {
+			fd, err := os.Open(q.segment(rseq))
+			if err != nil && rseq == wseq {
+				// wait for the writer to recreate it
+				select {
+				case <-q.notify:
+				case <-q.done:
+					return
+				}
+				continue
+			} else if err != nil {
+				rseq, roff = rseq+1, 0
+				continue
+			}
+			rfd = fd
+		}
+		rec, err := readRecord(rfd, roff, limit)
+		if err != nil {
+			// the rest of a corrupt segment cannot be framed
+			roff = limit
+			continue
+		}
+		if !q.deliver(rec) {
+			return
+		}
+		roff += recordHeaderSize + int64(len(rec))
+		if dirty = true; time.Since(saved) >= cursorInterval {
+			save()
+		}
+	}
+}
+
+// deliver writes rec to the output until it succeeds, it reports false
+// if the queue was closed first.
+func (q *DiskQueue) deliver(rec []byte) bool {
+	wait := queueRetryBackoff
+	for {
+		if _, err := q.out.Write(rec); err == nil {
+			return true
+		}
+		select {
+		case <-time.After(wait):
+		case <-q.done:
+			return false
+		}
+		if wait *= 2; wait > queueRetryMax {
+			wait = queueRetryMax
+		}
+	}
+}
+
+func (q *DiskQueue) loadCursor() (uint64, int64) {
+	data, err := os.ReadFile(filepath.Join(q.dir, cursorFile))
+	if err != nil {
+		return 0, 0
+	}
+	var (
+		seq uint64
+		off int64
+	)
+	if _, err := fmt.Sscanf(string(data), "%d %d", &seq, &off); err != nil {
+		return 0, 0
+	}
+	return seq, off
+}
+
+func (q *DiskQueue) saveCursor(seq uint64, off int64) {
+	path := filepath.Join(q.dir, cursorFile)
+	tmp := path + ".tmp"
+	fd, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	_, err = fmt.Fprintf(fd, "%d %d\n", seq, off)
+	if err == nil {
+		err = fd.Sync()
+	}
+	if cerr := fd.Close(); err != nil || cerr != nil {
+		return
+	}
+	if os.Rename(tmp, path) != nil {
+		return
+	}
+	if dir, err := os.Open(q.dir); err == nil {
+		_ = dir.Sync()
+		_ = dir.Close()
+	}
+}
+
+func (q *DiskQueue) Healthy() error {
+	q.mu.Lock()
+	closed, broken, seq := q.closed, q.wfd == nil, q.wseq
+	q.mu.Unlock()
+	if closed {
+		return errFileClosed
+	}
+	if broken {
+		return fmt.Errorf("logie: disk queue segment %d is not open", seq)
+	}
+	return probe(q.out)
+}
+
+// Close stops forwarding, entries not yet delivered stay on disk and are
+// sent by the next DiskQueue opened on the same dir.
+func (q *DiskQueue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	var err error
+	if q.wfd != nil {
+		err = q.wfd.Close()
+		q.wfd = nil
+	}
+	q.mu.Unlock()
+
+	close(q.done)
+	q.wg.Wait()
+	return err
+}
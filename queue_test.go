@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collector records the entries written to it, failing while fail is set.
+type collector struct {
+	mu      sync.Mutex
+	entries []string
+	fail    bool
+}
+
+func (c *collector) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fail {
+		return 0, errors.New("unavailable")
+	}
+	c.entries = append(c.entries, string(p))
+	return len(p), nil
+}
+
+func (c *collector) setFail(fail bool) {
+	c.mu.Lock()
+	c.fail = fail
+	c.mu.Unlock()
+}
+
+func (c *collector) got() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.entries...)
+}
+
+// waitFor polls cond for up to a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func writeEntries(t *testing.T, q *DiskQueue, entries ...string) {
+	t.Helper()
+	for _, e := range entries {
+		if _, err := q.Write([]byte(e)); err != nil {
+			t.Fatalf("Write(%q): %v", e, err)
+		}
+	}
+}
+
+func TestDiskQueueDelivers(t *testing.T) {
+	tests := []struct {
+		name    string
+		segSize int64
+	}{
+		{"single segment", 1 << 20},
+		{"segment per entry", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &collector{}
+			q, err := NewDiskQueue(t.TempDir(), out, WithSegmentSize(tt.segSize))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer q.Close()
+			writeEntries(t, q, "a\n", "b\n", "c\n")
+			waitFor(t, "delivery", func() bool { return len(out.got()) == 3 })
+			if got := out.got(); got[0] != "a\n" || got[1] != "b\n" || got[2] != "c\n" {
+				t.Errorf("delivered %q, want in order", got)
+			}
+		})
+	}
+}
+
+func TestDiskQueueResumes(t *testing.T) {
+	dir := t.TempDir()
+	down := &collector{fail: true}
+	q, err := NewDiskQueue(dir, down)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeEntries(t, q, "a\n", "b\n")
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := &collector{}
+	q, err = NewDiskQueue(dir, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "redelivery", func() bool { return len(out.got()) == 2 })
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the cursor saved once drained keeps entries from being sent twice
+	again := &collector{}
+	q, err = NewDiskQueue(dir, again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeEntries(t, q, "c\n")
+	waitFor(t, "new entry", func() bool { return len(again.got()) == 1 })
+	q.Close()
+	if got := again.got(); got[0] != "c\n" {
+		t.Errorf("delivered %q after restart, want only the new entry", got)
+	}
+}
+
+func TestDiskQueueRepairsTornTail(t *testing.T) {
+	dir := t.TempDir()
+	q, err := NewDiskQueue(dir, &collector{fail: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeEntries(t, q, "a\n", "b\n")
+	seg := q.segment(q.wseq)
+	q.Close()
+
+	// a crash in the middle of a record leaves half a header behind
+	fd, err := os.OpenFile(seg, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.Write([]byte{0, 0, 0})
+	fd.Close()
+
+	out := &collector{}
+	q, err = NewDiskQueue(dir, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	writeEntries(t, q, "c\n")
+	waitFor(t, "delivery", func() bool { return len(out.got()) == 3 })
+	if got := out.got(); got[2] != "c\n" {
+		t.Errorf("delivered %q, want the entry written after the repair last", got)
+	}
+}
+
+func TestDiskQueueRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []QueueOption
+		entry []byte
+		want  error
+	}{
+		{"over the disk usage", []QueueOption{WithMaxDiskUsage(recordHeaderSize + 4)}, []byte("12345"), errQueueFull},
+		{"over the record limit", nil, make([]byte, maxRecordSize+1), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := NewDiskQueue(t.TempDir(), &collector{fail: true}, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer q.Close()
+			_, err = q.Write(tt.entry)
+			if err == nil || (tt.want != nil && !errors.Is(err, tt.want)) {
+				t.Errorf("Write() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiskQueueCloseAfterFailedRollover(t *testing.T) {
+	dir := t.TempDir()
+	q, err := NewDiskQueue(dir, &collector{fail: true}, WithSegmentSize(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeEntries(t, q, "a\n")
+	// the next segment cannot be created
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Write([]byte("b\n")); err == nil {
+		t.Fatal("Write() succeeded without a segment")
+	}
+	if q.Healthy() == nil {
+		t.Error("Healthy() = nil without an open segment")
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		q.Close()
+		q.wg.Wait()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Close left the forwarding goroutine running")
+	}
+	if _, err := q.Write([]byte("c\n")); !errors.Is(err, errFileClosed) {
+		t.Errorf("Write() after Close error = %v, want errFileClosed", err)
+	}
+}
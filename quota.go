@@ -0,0 +1,71 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// quota counts bytes over fixed windows.
+type quota struct {
+	mu     sync.Mutex
+	limit  int64
+	window time.Duration
+	used   int64
+	start  time.Time
+}
+
+func newQuota(limit int64, window time.Duration) *quota {
+	if window <= 0 {
+		window = time.Hour
+	}
+	return &quota{limit: limit, window: window, start: time.Now()}
+}
+
+// take accounts n bytes and reports whether they fit in the current
+// window, exceeded is true only for the first refused take of a window.
+func (q *quota) take(now time.Time, n int64) (ok, exceeded bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if now.Sub(q.start) >= q.window {
+		q.start, q.used = now, 0
+	}
+	if q.used+n > q.limit {
+		exceeded = q.used <= q.limit
+		q.used = q.limit + 1
+		return false, exceeded
+	}
+	q.used += n
+	return true, false
+}
+
+// WithQuota limits the bytes emitted per hour, once exceeded only Error
+// and above are written until the window resets, a single notice is
+// logged when that happens.
+func WithQuota(bytesPerHour int64) Option {
+	return func(o *options) {
+		o.quota = newQuota(bytesPerHour, time.Hour)
+	}
+}
+
+// overQuota accounts the formatted entry and reports whether it must be
+// dropped.
+func (e *Entry) overQuota() bool {
+	q := e.logger.opt.quota
+	if q == nil {
+		return false
+	}
+	if e.Level >= ErrorLevel {
+		q.mu.Lock()
+		q.used += int64(e.Buf.Len())
+		q.mu.Unlock()
+		return false
+	}
+
+	ok, exceeded := q.take(e.Time, int64(e.Buf.Len()))
+	if exceeded {
+		nl := e.logger.derive(func(o *options) { o.quota = nil })
+		nl.WithFields(Fields{"quota_bytes": q.limit}).entry().write(WarnLevel, FmtEmptySeparate,
+			"log quota exceeded, dropping entries below Error until the window resets")
+	}
+	return !ok
+}
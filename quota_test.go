@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQuota(t *testing.T) {
+	const notice = "log quota exceeded, dropping entries below Error until the window resets quota_bytes=10\n"
+	tests := []struct {
+		name string
+		log  func(l *Logger, advance func(time.Duration))
+		want []string
+	}{
+		{
+			name: "within quota",
+			log: func(l *Logger, _ func(time.Duration)) {
+				l.Info("1234")
+				l.Info("1234")
+			},
+			want: []string{"1234\n", "1234\n"},
+		},
+		{
+			name: "exceeded once",
+			log: func(l *Logger, _ func(time.Duration)) {
+				l.Info("12345678")
+				l.Info("dropped")
+				l.Warn("dropped too")
+				l.Error("kept")
+			},
+			want: []string{"12345678\n", notice, "kept\n"},
+		},
+		{
+			name: "errors use the quota",
+			log: func(l *Logger, _ func(time.Duration)) {
+				l.Error("123456789")
+				l.Info("dropped")
+			},
+			want: []string{"123456789\n", notice},
+		},
+		{
+			name: "window reset",
+			log: func(l *Logger, advance func(time.Duration)) {
+				l.Info("12345678")
+				l.Info("dropped")
+				advance(time.Hour + time.Second)
+				l.Info("again")
+				l.Info("dropped")
+			},
+			want: []string{"12345678\n", notice, "again\n", notice},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &collector{}
+			now := time.Now()
+			l := New(WithPosition(out), WithFormatter(&TextFormatter{IgnoreBasicFields: true}),
+				WithQuota(10), WithClock(func() time.Time { return now }))
+			tt.log(l, func(d time.Duration) { now = now.Add(d) })
+			if got := out.got(); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("logged %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type RedisOption func(*RedisStreamSink)
+
+// WithMaxLen trims the stream to about n entries on every XADD, exact
+// trimming is slower and rarely needed.
+func WithMaxLen(n int64, exact bool) RedisOption {
+	return func(s *RedisStreamSink) {
+		s.maxLen, s.exact = n, exact
+	}
+}
+
+// WithPipeline sends up to n entries per round trip.
+func WithPipeline(n int) RedisOption {
+	return func(s *RedisStreamSink) {
+		s.pipeline = n
+	}
+}
+
+// WithRedisFlushInterval sets how often pending entries are sent, one
+// second by default or when d is not positive.
+func WithRedisFlushInterval(d time.Duration) RedisOption {
+	return func(s *RedisStreamSink) {
+		s.interval = d
+	}
+}
+
+func WithRedisTLS(c *TLSConfig) RedisOption {
+	return func(s *RedisStreamSink) {
+		s.tls = c
+	}
+}
+
+// WithRedisErrorHandler receives the errors of background flushes.
+func WithRedisErrorHandler(fn func(error)) RedisOption {
+	return func(s *RedisStreamSink) {
+		s.onError = fn
+	}
+}
+
+// RedisStreamSink appends entries to a Redis stream with XADD, as the
+// entry field of each stream item. Entries are pipelined: they are sent
+// once Pipeline of them are pending and every flush interval. A failed
+// connection is reestablished and the batch sent again once.
+type RedisStreamSink struct {
+	mu       sync.Mutex
+	addr     string
+	user     *url.Userinfo
+	db       int
+	tls      *TLSConfig
+	stream   string
+	maxLen   int64
+	exact    bool
+	pipeline int
+	interval time.Duration
+	onError  func(error)
+
+	conn    net.Conn
+	r       *bufio.Reader
+	w       *bufio.Writer
+	pending [][]byte
+	done    chan struct{}
+	wg      sync.WaitGroup
+	closing sync.Once
+}
+
+// NewRedisStreamSink connects to a server URL such as
+// "redis://:password@host:6379/0", rediss enables TLS.
+func NewRedisStreamSink(serverURL, stream string, opts ...RedisOption) (*RedisStreamSink, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, err
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	s := &RedisStreamSink{
+		addr:     addr,
+		user:     u.User,
+		stream:   stream,
+		pipeline: 100,
+		interval: time.Second,
+		done:     make(chan struct{}),
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if s.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("logie: invalid redis database %q", db)
+		}
+	}
+	if u.Scheme == "rediss" {
+		s.tls = &TLSConfig{}
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.interval <= 0 {
+		s.interval = time.Second
+	}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s, nil
+}
+
+func (s *RedisStreamSink) connect() error {
+	var (
+		conn net.Conn
+		err  error
+	)
+	if s.tls != nil {
+		cfg, berr := s.tls.Build()
+		if berr != nil {
+			return berr
+		}
+		if cfg.ServerName == "" {
+			cfg.ServerName, _, _ = net.SplitHostPort(s.addr)
+		}
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}, "tcp", s.addr, cfg)
+	} else {
+		conn, err = net.DialTimeout("tcp", s.addr, 10*time.Second)
+	}
+	if err != nil {
+		return err
+	}
+	s.conn, s.r, s.w = conn, bufio.NewReader(conn), bufio.NewWriter(conn)
+
+	var setup [][]string
+	if s.user != nil {
+		if pass, ok := s.user.Password(); ok {
+			if name := s.user.Username(); name != "" {
+				setup = append(setup, []string{"AUTH", name, pass})
+			} else {
+				setup = append(setup, []string{"AUTH", pass})
+			}
+		}
+	}
+	if s.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(s.db)})
+	}
+	for _, cmd := range setup {
+		args := make([][]byte, len(cmd))
+		for i, a := range cmd {
+			args[i] = []byte(a)
+		}
+		writeRESP(s.w, args)
+	}
+	if err := s.w.Flush(); err != nil {
+		s.disconnect()
+		return err
+	}
+	for range setup {
+		if err := readRESP(s.r); err != nil {
+			s.disconnect()
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *RedisStreamSink) disconnect() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
+
+func (s *RedisStreamSink) run() {
+	defer s.wg.Done()
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := s.Flush(); err != nil && s.onError != nil {
+				s.onError(err)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *RedisStreamSink) Write(p []byte) (int, error) {
+	entry := append([]byte(nil), bytes.TrimSuffix(p, []byte("\n"))...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = append(s.pending, entry)
+	if len(s.pending) >= s.pipeline {
+		if err := s.flush(); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends the pending entries.
+func (s *RedisStreamSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flush()
+}
+
+func (s *RedisStreamSink) flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	err := s.send()
+	if err != nil && !isRedisError(err) {
+		// the connection failed, retry once on a new one
+		s.disconnect()
+		err = s.send()
+	}
+	s.pending = s.pending[:0]
+	return err
+}
+
+// send pipelines an XADD per pending entry and reads the replies.
+func (s *RedisStreamSink) send() error {
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+	head := [][]byte{[]byte("XADD"), []byte(s.stream)}
+	if s.maxLen > 0 {
+		head = append(head, []byte("MAXLEN"))
+		if !s.exact {
+			head = append(head, []byte("~"))
+		}
+		head = append(head, []byte(strconv.FormatInt(s.maxLen, 10)))
+	}
+	head = append(head, []byte("*"), []byte("entry"))
+	for _, entry := range s.pending {
+		writeRESP(s.w, append(head, entry))
+	}
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+
+	var first error
+	for range s.pending {
+		if err := readRESP(s.r); err != nil {
+			if !isRedisError(err) {
+				return err
+			}
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+func (s *RedisStreamSink) Healthy() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return probeSinkConn(s.conn)
+}
+
+// Close flushes the pending entries and closes the connection.
+func (s *RedisStreamSink) Close() error {
+	var err error
+	s.closing.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		err = s.flush()
+		s.disconnect()
+	})
+	return err
+}
+
+type redisError string
+
+func (e redisError) Error() string {
+	return "logie: redis: " + string(e)
+}
+
+func isRedisError(err error) bool {
+	var re redisError
+	return errors.As(err, &re)
+}
+
+func writeRESP(w *bufio.Writer, args [][]byte) {
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(w, "$%d\r\n", len(a))
+		_, _ = w.Write(a)
+		_, _ = w.WriteString("\r\n")
+	}
+}
+
+// readRESP consumes a reply, returning it as an error when it is one.
+func readRESP(r *bufio.Reader) error {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return io.ErrUnexpectedEOF
+	}
+	switch line[0] {
+	case '+', ':':
+		return nil
+	case '-':
+		return redisError(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return nil
+		}
+		_, err = io.CopyN(io.Discard, r, int64(n)+2)
+		return err
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			if err := readRESP(r); err != nil && !isRedisError(err) {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("logie: redis: unexpected reply %q", line)
+}
+
+// openRedisSink understands the stream, maxlen and pipeline query
+// parameters, e.g. "redis://host:6379/0?stream=logs&maxlen=100000".
+func openRedisSink(u *url.URL) (io.Writer, error) {
+	q := u.Query()
+	stream := q.Get("stream")
+	if stream == "" {
+		return nil, fmt.Errorf("logie: redis sink %q has no stream", u.Redacted())
+	}
+	var opts []RedisOption
+	if v := q.Get("maxlen"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("logie: invalid stream maxlen %q", v)
+		}
+		opts = append(opts, WithMaxLen(n, false))
+	}
+	if v := q.Get("pipeline"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("logie: invalid pipeline size %q", v)
+		}
+		opts = append(opts, WithPipeline(n))
+	}
+	endpoint := *u
+	endpoint.RawQuery = ""
+	return NewRedisStreamSink(endpoint.String(), stream, opts...)
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// respServer accepts RESP commands and answers them like Redis, keeping
+// them in order.
+type respServer struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	commands [][]string
+	// reply answers a command, "+OK" by default
+	reply func(cmd []string) string
+}
+
+func newRESPServer(t *testing.T) *respServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &respServer{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *respServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		cmd, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, cmd)
+		reply := s.reply
+		s.mu.Unlock()
+		out := "+OK"
+		if reply != nil {
+			out = reply(cmd)
+		}
+		if _, err := io.WriteString(conn, out+"\r\n"); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line)[1:])
+	if err != nil {
+		return nil, err
+	}
+	cmd := make([]string, n)
+	for i := range cmd {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line)[1:])
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		cmd[i] = string(buf[:size])
+	}
+	return cmd, nil
+}
+
+func (s *respServer) got() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]string(nil), s.commands...)
+}
+
+func TestRedisStreamSink(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		opts []RedisOption
+		want []string
+	}{
+		{
+			name: "plain",
+			url:  "redis://%s",
+			want: []string{"XADD logs * entry a", "XADD logs * entry b"},
+		},
+		{
+			name: "auth, database and trimming",
+			url:  "redis://:secret@%s/2",
+			opts: []RedisOption{WithMaxLen(100, false)},
+			want: []string{"AUTH secret", "SELECT 2", "XADD logs MAXLEN ~ 100 * entry a", "XADD logs MAXLEN ~ 100 * entry b"},
+		},
+		{
+			name: "exact trimming",
+			url:  "redis://%s",
+			opts: []RedisOption{WithMaxLen(5, true)},
+			want: []string{"XADD logs MAXLEN 5 * entry a", "XADD logs MAXLEN 5 * entry b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newRESPServer(t)
+			s, err := NewRedisStreamSink(fmt.Sprintf(tt.url, srv.ln.Addr()), "logs", tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.Write([]byte("a\n"))
+			s.Write([]byte("b\n"))
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, cmd := range srv.got() {
+				got = append(got, strings.Join(cmd, " "))
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("commands %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedisStreamSinkServerError(t *testing.T) {
+	srv := newRESPServer(t)
+	srv.reply = func(cmd []string) string {
+		if cmd[0] == "XADD" && cmd[len(cmd)-1] == "bad" {
+			return "-ERR rejected"
+		}
+		return "$3\r\n1-0"
+	}
+	s, err := NewRedisStreamSink("redis://"+srv.ln.Addr().String(), "logs", WithPipeline(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Write([]byte("good\n"))
+	s.Write([]byte("bad\n"))
+	if _, err := s.Write([]byte("good\n")); !isRedisError(err) {
+		t.Errorf("Write() error = %v, want the redis error", err)
+	}
+	// a server error does not resend the pipeline on a new connection
+	if n := len(srv.got()); n != 3 {
+		t.Errorf("server got %d commands, want 3", n)
+	}
+}
+
+func TestRedisStreamSinkConcurrentClose(t *testing.T) {
+	srv := newRESPServer(t)
+	s, err := NewRedisStreamSink("redis://"+srv.ln.Addr().String(), "logs", WithRedisFlushInterval(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Write([]byte("a\n"))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Close()
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("concurrent Close calls did not return")
+	}
+	if n := len(srv.got()); n != 1 {
+		t.Errorf("server got %d commands, want the pending entry once", n)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	formattersMu sync.RWMutex
+	formatters   = map[string]func(cfg map[string]any) Formatter{
+		"text": func(cfg map[string]any) Formatter {
+			return &TextFormatter{IgnoreBasicFields: cfgBool(cfg, "ignore_basic_fields")}
+		},
+		"json": func(cfg map[string]any) Formatter {
+			return &JSONFormatter{IgnoreBasicFields: cfgBool(cfg, "ignore_basic_fields")}
+		},
+		"syslog": func(cfg map[string]any) Formatter {
+			return &SyslogFormatter{
+				Facility: cfgInt(cfg, "facility"),
+				SDID:     cfgString(cfg, "sd_id"),
+				AppName:  cfgString(cfg, "app_name"),
+				MsgID:    cfgString(cfg, "msg_id"),
+			}
+		},
+	}
+)
+
+// RegisterFormatter makes a formatter available to FormatterByName,
+// registering a name twice replaces the previous factory.
+func RegisterFormatter(name string, factory func(cfg map[string]any) Formatter) {
+	formattersMu.Lock()
+	formatters[name] = factory
+	formattersMu.Unlock()
+}
+
+// FormatterByName builds the formatter registered as name, cfg is passed
+// to its factory and may be nil. A time_zone entry, an IANA name or
+// "Local", applies to any formatter, see InLocation.
+func FormatterByName(name string, cfg ...map[string]any) (Formatter, error) {
+	formattersMu.RLock()
+	factory, ok := formatters[name]
+	formattersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("logie: unknown formatter %q", name)
+	}
+	var c map[string]any
+	if len(cfg) > 0 {
+		c = cfg[0]
+	}
+	f := factory(c)
+	if tz := cfgString(c, "time_zone"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("logie: invalid time zone %q: %w", tz, err)
+		}
+		f = InLocation(loc, f)
+	}
+	return f, nil
+}
+
+func cfgBool(cfg map[string]any, key string) bool {
+	b, _ := cfg[key].(bool)
+	return b
+}
+
+func cfgString(cfg map[string]any, key string) string {
+	s, _ := cfg[key].(string)
+	return s
+}
+
+// cfgInt accepts the float64 of decoded JSON as well.
+func cfgInt(cfg map[string]any, key string) int {
+	switch v := cfg[key].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
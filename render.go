@@ -0,0 +1,32 @@
+package main
+
+// Render formats an entry of lvl with msg and fields as l would write it,
+// without writing it anywhere: no output, route, hook or counter sees
+// it. Useful for tests and previews of a configuration. A formatter error
+// is returned along with the entry when it was still encoded.
+func (l *Logger) Render(lvl Level, msg string, fields Fields) (out []byte, err error) {
+	e := l.entry()
+	defer e.release()
+	e.Time = l.now()
+	e.Level = lvl
+	e.Format = FmtEmptySeparate
+	e.Args = []any{msg}
+	e.Fields = l.normalize(l.prepareFields(mergeFields(l.fields, fields)))
+	e.addFingerprint()
+	e.localize()
+	e.addRunbook()
+	e.addPartition()
+
+	defer func() {
+		if v := recover(); v != nil {
+			err = newPanicError("formatter", v)
+		}
+	}()
+	ferr := l.opt.formatter.Format(e)
+	if ferr != nil && e.Buf.Len() == 0 {
+		return nil, ferr
+	}
+	e.scanSecrets()
+	e.limitLine()
+	return append([]byte(nil), e.Buf.Bytes()...), ferr
+}
@@ -0,0 +1,58 @@
+package main
+
+import "fmt"
+
+type ReservedPolicy uint8
+
+const (
+	// ReservedPrefix keeps both values, renaming the user key with
+	// JSONFormatter.FieldPrefix.
+	ReservedPrefix ReservedPolicy = iota
+	// ReservedError reports the collision through OnError, the entry is
+	// still written with the user key renamed as under ReservedPrefix.
+	ReservedError
+	// ReservedOverride lets the user value replace the basic field.
+	ReservedOverride
+	// ReservedDrop keeps the basic field and discards the user value.
+	ReservedDrop
+)
+
+var reservedKeys = map[string]struct{}{
+	"schema_version": {},
+	"level":          {},
+	"time":           {},
+	"file":           {},
+	"func":           {},
+	"message":        {},
+	"errors":         {},
+}
+
+func (f *JSONFormatter) mergeFields(e *Entry) error {
+	var err error
+	for k, v := range e.Fields {
+		v, ok := f.value(jsonValue(v))
+		if !ok {
+			continue
+		}
+		if _, ok := reservedKeys[k]; !ok {
+			e.Map[k] = v
+			continue
+		}
+
+		switch f.ReservedKeys {
+		case ReservedOverride:
+			e.Map[k] = v
+		case ReservedDrop:
+		default:
+			if f.ReservedKeys == ReservedError && err == nil {
+				err = fmt.Errorf("logie: field %q collides with a reserved key", k)
+			}
+			prefix := f.FieldPrefix
+			if prefix == "" {
+				prefix = "fields."
+			}
+			e.Map[prefix+k] = v
+		}
+	}
+	return err
+}
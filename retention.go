@@ -0,0 +1,149 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RetentionPolicy selects the rotated files of a FileWriter to remove.
+// Files are gzipped into ArchiveDir instead of deleted when it is set.
+type RetentionPolicy struct {
+	MaxAge       time.Duration
+	MaxTotalSize int64
+	ArchiveDir   string
+	Interval     time.Duration
+}
+
+var retentionMu sync.Mutex
+
+type retention struct {
+	w *FileWriter
+	p RetentionPolicy
+}
+
+// WithRetention makes New apply p to the backups of w every p.Interval
+// (one hour by default), it stops when the logger is closed.
+func WithRetention(w *FileWriter, p RetentionPolicy) Option {
+	return func(o *options) {
+		o.retention = append(o.retention, retention{w: w, p: p})
+	}
+}
+
+func (l *Logger) startRetention(r retention) {
+	interval := r.p.Interval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				_ = r.w.ApplyRetention(r.p)
+			case <-done:
+				return
+			}
+		}
+	}()
+	l.opt.closers = append(l.opt.closers, func() error {
+		close(done)
+		return nil
+	})
+}
+
+// ApplyRetention removes or archives the backups of w exceeding p. Backups
+// are never written again once rotated, so this is safe to run while w
+// keeps logging and rotating.
+func (w *FileWriter) ApplyRetention(p RetentionPolicy) error {
+	retentionMu.Lock()
+	defer retentionMu.Unlock()
+
+	backups, err := w.Backups()
+	if err != nil {
+		return err
+	}
+
+	var (
+		total   int64
+		expired []string
+		now     = time.Now()
+	)
+	for i := len(backups) - 1; i >= 0; i-- {
+		fi, err := os.Stat(backups[i])
+		if err != nil {
+			continue
+		}
+		total += fi.Size()
+		if (p.MaxAge > 0 && now.Sub(fi.ModTime()) > p.MaxAge) || (p.MaxTotalSize > 0 && total > p.MaxTotalSize) {
+			expired = append(expired, backups[i])
+		}
+	}
+
+	var first error
+	for _, path := range expired {
+		if err := retire(path, p.ArchiveDir); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func retire(path, archiveDir string) error {
+	if archiveDir == "" {
+		return os.Remove(path)
+	}
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		return err
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst := filepath.Join(archiveDir, filepath.Base(path)+".gz")
+	fd, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(fd)
+	if _, err = io.Copy(zw, src); err == nil {
+		err = zw.Close()
+	}
+	if cerr := fd.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(dst)
+		return err
+	}
+	return os.Remove(path)
+}
+
+func Close() error {
+	return std.Close()
+}
+
+// Close stops the background tasks started by the options of l. Outputs
+// are left open, they belong to the caller.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	closers := l.opt.closers
+	l.opt.closers = nil
+	l.mu.Unlock()
+
+	var first error
+	for _, c := range closers {
+		if err := c(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// retryQueueSize bounds the failed writes waiting for the retry goroutine.
+const retryQueueSize = 1024
+
+type retryPolicy struct {
+	attempts int
+	backoff  time.Duration
+	max      time.Duration
+
+	// writes failing on the goroutine of the caller are retried by a
+	// goroutine started on the first failure
+	once   sync.Once
+	mu     sync.RWMutex
+	ch     chan retryEntry
+	closed bool
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type retryEntry struct {
+	asyncEntry
+	err error
+}
+
+// WithRetry retries a failed write up to attempts times, doubling the
+// backoff between tries up to one minute. Without WithAsync the retries
+// run on a goroutine of their own, the caller does not wait for them,
+// and entries go to the dead letter at once while it is backed up or
+// after Close.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.retry = &retryPolicy{attempts: attempts, backoff: backoff, max: time.Minute}
+	}
+}
+
+// WithDeadLetter spills entries that still fail after retrying into the
+// file at path, see Replay. Entries are stored as records framed like
+// the ones of DiskQueue, so binary and multi-line entries come back
+// whole.
+func WithDeadLetter(path string) Option {
+	return func(o *options) {
+		o.deadLetter = &deadLetter{path: path}
+	}
+}
+
+type deadLetter struct {
+	mu   sync.Mutex
+	path string
+	fd   *os.File
+	// replay serializes Replay calls
+	replay sync.Mutex
+}
+
+func (d *deadLetter) spill(p []byte) error {
+	if len(p) > maxRecordSize {
+		return fmt.Errorf("logie: entry of %d bytes exceeds the dead letter record limit", len(p))
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.fd == nil {
+		if err := d.open(); err != nil {
+			return err
+		}
+	}
+	_, err := d.fd.Write(newRecord(p))
+	return err
+}
+
+// open opens the file for appending, cutting off a record torn by a crash
+// during a spill so the next ones stay framed.
+func (d *deadLetter) open() error {
+	fd, err := os.OpenFile(d.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := fd.Stat()
+	if err != nil {
+		_ = fd.Close()
+		return err
+	}
+	var off int64
+	for off < fi.Size() {
+		rec, err := readRecord(fd, off, fi.Size())
+		if err != nil {
+			break
+		}
+		off += recordHeaderSize + int64(len(rec))
+	}
+	if off < fi.Size() {
+		if err := fd.Truncate(off); err != nil {
+			_ = fd.Close()
+			return err
+		}
+	}
+	d.fd = fd
+	return nil
+}
+
+func (d *deadLetter) close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.fd == nil {
+		return nil
+	}
+	err := d.fd.Close()
+	d.fd = nil
+	return err
+}
+
+func (l *Logger) startRetry() {
+	r := l.opt.retry
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	l.opt.closers = append(l.opt.closers, func() error {
+		// pending entries go to the dead letter without waiting
+		r.cancel()
+		r.mu.Lock()
+		r.closed = true
+		if r.ch != nil {
+			close(r.ch)
+		}
+		r.mu.Unlock()
+		r.wg.Wait()
+		return nil
+	})
+}
+
+// later hands a write of l that failed with err to the retry goroutine
+// and reports whether it took it.
+func (r *retryPolicy) later(l *Logger, lvl Level, p []byte, err error) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed || r.ctx == nil {
+		return false
+	}
+	r.once.Do(func() {
+		r.ch = make(chan retryEntry, retryQueueSize)
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			for re := range r.ch {
+				if err := re.logger.deliver(r.ctx, re.lvl, re.buf, re.err, true); err != nil {
+					re.logger.reportError(err)
+				}
+			}
+		}()
+	})
+	select {
+	case r.ch <- retryEntry{asyncEntry{logger: l, lvl: lvl, buf: append([]byte(nil), p...)}, err}:
+		return true
+	default:
+		return false
+	}
+}
+
+// output writes p to the configured position, applying the retry policy
+// and dead-letter spill. It takes l.mu for each write only, the backoff
+// and the spill run without it so other goroutines keep logging.
+func (l *Logger) output(ctx context.Context, lvl Level, p []byte) error {
+	return l.deliver(ctx, lvl, p, l.lockedWrite(lvl, p), true)
+}
+
+// outputSync is output for the goroutine logging the entry, the retries
+// of a failed write go to the retry goroutine instead of sleeping here.
+func (l *Logger) outputSync(ctx context.Context, lvl Level, p []byte) error {
+	err := l.lockedWrite(lvl, p)
+	r := l.opt.retry
+	if err == nil || r == nil {
+		return l.deliver(ctx, lvl, p, err, true)
+	}
+	if r.later(l, lvl, p, err) {
+		return nil
+	}
+	return l.deliver(ctx, lvl, p, err, false)
+}
+
+// deliver completes a write whose first attempt returned err, retrying it
+// when retry is set and spilling it to the dead letter if it still fails.
+func (l *Logger) deliver(ctx context.Context, lvl Level, p []byte, err error, retry bool) error {
+	if err == nil {
+		if l.opt.stats != nil {
+			l.opt.stats.record(l.outputName(lvl), lvl, len(p), nil)
+		}
+		return nil
+	}
+	if l.opt.stats != nil {
+		defer func() {
+			l.opt.stats.record(l.outputName(lvl), lvl, len(p), err)
+		}()
+	}
+
+	if r := l.opt.retry; r != nil && retry {
+		wait := r.backoff
+		for i := 0; i < r.attempts && err != nil; i++ {
+			if cerr := sleepCtx(ctx, wait); cerr != nil {
+				err = fmt.Errorf("%w (retry: %v)", err, cerr)
+				break
+			}
+			if wait *= 2; wait > r.max {
+				wait = r.max
+			}
+			err = l.lockedWrite(lvl, p)
+		}
+	}
+
+	if err != nil && l.opt.deadLetter != nil {
+		if serr := l.opt.deadLetter.spill(p); serr != nil {
+			return fmt.Errorf("%w (dead letter: %v)", err, serr)
+		}
+	}
+	return err
+}
+
+func (l *Logger) outputName(lvl Level) string {
+	if l.opt.outputName != "" {
+		return l.opt.outputName
+	}
+	return describeOutput(l.destination(lvl))
+}
+
+func (l *Logger) lockedWrite(lvl Level, p []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var start time.Time
+	if l.opt.shed != nil {
+		start = time.Now()
+	}
+	err := l.writePosition(lvl, p)
+	if l.opt.shed != nil {
+		l.opt.shed.observe(time.Since(start))
+	}
+	return err
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if ctx == nil {
+		time.Sleep(d)
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func writeFull(w io.Writer, lvl Level, p []byte) error {
+	var (
+		n   int
+		err error
+	)
+	if lw, ok := w.(LevelWriter); ok {
+		n, err = lw.WriteLevel(lvl, p)
+	} else {
+		n, err = w.Write(p)
+	}
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+	return err
+}
+
+func Replay() error {
+	return std.Replay()
+}
+
+// Replay re-sends the entries spilled to the dead-letter file to the
+// configured position. Delivered entries are removed from the file, when
+// a write fails the remaining ones are kept for the next Replay.
+func (l *Logger) Replay() error {
+	dl := l.opt.deadLetter
+	if dl == nil {
+		return nil
+	}
+	dl.replay.Lock()
+	defer dl.replay.Unlock()
+
+	// the records are copied out so spills and writes do not wait for
+	// each other
+	if err := dl.close(); err != nil {
+		return err
+	}
+	dl.mu.Lock()
+	data, err := os.ReadFile(dl.path)
+	dl.mu.Unlock()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var (
+		delivered int64
+		werr      error
+		size      = int64(len(data))
+		r         = bytes.NewReader(data)
+	)
+	for delivered < size {
+		rec, err := readRecord(r, delivered, size)
+		if err != nil {
+			// a crash during a spill left a torn record at the end
+			werr = fmt.Errorf("logie: dropped %d bytes of a torn dead letter record", size-delivered)
+			delivered = size
+			break
+		}
+		l.mu.Lock()
+		werr = writeFull(l.destination(l.opt.stdLevel), l.opt.stdLevel, rec)
+		l.mu.Unlock()
+		if werr != nil {
+			break
+		}
+		delivered += recordHeaderSize + int64(len(rec))
+	}
+	if err := dl.drop(delivered); err != nil {
+		return err
+	}
+	return werr
+}
+
+// drop removes the first n bytes of the file, spills appended since they
+// were read are kept. The rest is written to a temporary file renamed
+// over the old one, a crash leaves either of them whole.
+func (d *deadLetter) drop(n int64) error {
+	if n == 0 {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.fd != nil {
+		if err := d.fd.Close(); err != nil {
+			return err
+		}
+		d.fd = nil
+	}
+	data, err := os.ReadFile(d.path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(d.path), filepath.Base(d.path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data[n:])
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), d.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
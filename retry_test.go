@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetryDoesNotBlockCaller(t *testing.T) {
+	out := &collector{fail: true}
+	l := New(WithPosition(out), WithFormatter(&TextFormatter{IgnoreBasicFields: true}),
+		WithRetry(5, 100*time.Millisecond))
+	defer l.Close()
+
+	start := time.Now()
+	l.Info("later")
+	if d := time.Since(start); d > 50*time.Millisecond {
+		t.Errorf("Info took %v with the output down, want the retries in the background", d)
+	}
+	out.setFail(false)
+	waitFor(t, "retried entry", func() bool { return len(out.got()) == 1 })
+}
+
+func TestDeadLetterReplay(t *testing.T) {
+	entries := []string{"multi\nline\n", "\x00\x01binary\n", "plain\n"}
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"without retry", nil},
+		{"after retrying", []Option{WithRetry(2, time.Millisecond)}},
+		{"async", []Option{WithRetry(1, time.Millisecond), WithAsync(16)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "dead.letter")
+			out := &collector{fail: true}
+			opts := append([]Option{WithPosition(out), WithFormatter(&TextFormatter{IgnoreBasicFields: true}),
+				WithDeadLetter(path), WithOnError(func(error) {})}, tt.opts...)
+			l := New(opts...)
+			for _, e := range entries {
+				l.Info(e[:len(e)-1])
+			}
+			// Close drains the async queue and the retries into the file
+			l.Close()
+
+			out.setFail(false)
+			if err := l.Replay(); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.got(); !reflect.DeepEqual(got, entries) {
+				t.Errorf("replayed %q, want %q", got, entries)
+			}
+			if data, _ := os.ReadFile(path); len(data) != 0 {
+				t.Errorf("dead letter keeps %d bytes after a full replay", len(data))
+			}
+		})
+	}
+}
+
+func TestDeadLetterKeepsUndelivered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead.letter")
+	out := &collector{fail: true}
+	l := New(WithPosition(out), WithFormatter(&TextFormatter{IgnoreBasicFields: true}),
+		WithDeadLetter(path), WithOnError(func(error) {}))
+	l.Info("first")
+	l.Info("second")
+	if err := l.Replay(); err == nil {
+		t.Fatal("Replay() succeeded with the output down")
+	}
+	out.setFail(false)
+	if err := l.Replay(); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.got(); len(got) != 2 {
+		t.Errorf("replayed %q, want both entries once", got)
+	}
+}
+
+func TestDeadLetterTornRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead.letter")
+	torn := append(newRecord([]byte("whole\n")), newRecord([]byte("torn\n"))[:6]...)
+	if err := os.WriteFile(path, torn, 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := &collector{fail: true}
+	l := New(WithPosition(out), WithFormatter(&TextFormatter{IgnoreBasicFields: true}),
+		WithDeadLetter(path), WithOnError(func(error) {}))
+	// a spill after the crash cuts the torn record off first
+	l.Info("next")
+	out.setFail(false)
+	if err := l.Replay(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.got(), []string{"whole\n", "next\n"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+type rotateMode uint8
+
+const (
+	rotateRename rotateMode = iota
+	rotateSymlink
+	rotateCopyTruncate
+)
+
+// WithSymlink writes to timestamped files and keeps path as a symlink to
+// the active one, rotation only opens a new file and moves the link.
+func WithSymlink() FileOption {
+	return func(w *FileWriter) {
+		w.mode = rotateSymlink
+	}
+}
+
+// WithCopyTruncate rotates by copying the file aside and truncating it in
+// place, for collectors that keep the file open.
+func WithCopyTruncate() FileOption {
+	return func(w *FileWriter) {
+		w.mode = rotateCopyTruncate
+	}
+}
+
+// relink atomically points link at target, which is resolved relative to
+// the directory of link.
+func relink(target, link string) error {
+	tmp := link + ".tmp"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, link)
+}
+
+func (w *FileWriter) copyTruncate() error {
+	src, err := os.Open(w.path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	backup := w.path + "." + time.Now().Format(backupTimeFormat)
+	dst, err := os.OpenFile(backup, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dst, src); err == nil {
+		err = dst.Sync()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(backup)
+		return err
+	}
+
+	if err := w.fd.Truncate(0); err != nil {
+		return err
+	}
+	w.size = 0
+	return nil
+}
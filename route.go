@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+func Named(name string) *Logger {
+	return std.Named(name)
+}
+
+// Named returns a child logger whose name is appended to the one of l
+// with a dot, it is logged as the logger field and used by routes.
+func (l *Logger) Named(name string) *Logger {
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	child := l.WithFields(Fields{"logger": name})
+	child.name = name
+	return child
+}
+
+func (l *Logger) Name() string {
+	return l.name
+}
+
+// Route sends the entries it matches to an extra sink and/or hook, in
+// addition to the logger output. An entry matches when its level is at
+// least MinLevel, its logger name starts with Logger and every predicate
+// in Match accepts its fields.
+//
+// With Transforms or Formatter set, the route works on a copy of the
+// entry: transforms run in order, then the copy is formatted again with
+// Formatter, or the logger formatter when nil.
+//
+// Sink is written like the logger output, through the async queue, retry
+// policy, write timeout and watchdog of the first logger using the route.
+// Entries still failing after the retries are not spilled to the dead
+// letter, Replay would send them to the logger output.
+type Route struct {
+	Name       string
+	MinLevel   Level
+	Logger     string
+	Match      []func(fields Fields) bool
+	Transforms []Transform
+	Formatter  Formatter
+	Sink       io.Writer
+	Hook       func(e *Entry)
+
+	// mu serializes the writes to Sink
+	mu   sync.Mutex
+	once sync.Once
+	out  *Logger
+}
+
+// WithRoutes adds routing rules evaluated for every entry, in order.
+func WithRoutes(routes ...*Route) Option {
+	return func(o *options) {
+		o.routes = append(o.routes, routes...)
+	}
+}
+
+// FieldEquals matches entries whose field key equals value.
+func FieldEquals(key string, value any) func(Fields) bool {
+	return func(f Fields) bool {
+		v, ok := f[key]
+		return ok && fmt.Sprint(v) == fmt.Sprint(value)
+	}
+}
+
+// FieldExists matches entries having the field key.
+func FieldExists(key string) func(Fields) bool {
+	return func(f Fields) bool {
+		_, ok := f[key]
+		return ok
+	}
+}
+
+// FieldPrefix matches entries whose field key is a string starting with
+// prefix.
+func FieldPrefix(key, prefix string) func(Fields) bool {
+	return func(f Fields) bool {
+		s, ok := f[key].(string)
+		return ok && strings.HasPrefix(s, prefix)
+	}
+}
+
+func (r *Route) matches(e *Entry) bool {
+	if e.Level < r.MinLevel || !strings.HasPrefix(e.logger.name, r.Logger) {
+		return false
+	}
+	for _, match := range r.Match {
+		if !match(e.Fields) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Route) callHook(e *Entry) {
+	defer func() {
+		if v := recover(); v != nil {
+			e.logger.reportError(newPanicError("route "+r.Name+" hook", v))
+		}
+	}()
+	r.Hook(e)
+}
+
+// output returns the logger writing to Sink. It shares the async queue
+// and retry policy of l, with a lock, watchdog and stats entry of its own
+// so a slow sink does not hold back the logger output.
+func (r *Route) output(l *Logger) *Logger {
+	r.once.Do(func() {
+		r.out = l.derive(func(o *options) {
+			o.position, o.outputFunc = r.Sink, nil
+			o.outputName = "route:" + r.Name
+			o.deadLetter, o.shed = nil, nil
+			if o.watchdog != nil {
+				o.watchdog = &watchdog{threshold: o.watchdog.threshold}
+			}
+		})
+		r.out.mu = &r.mu
+	})
+	return r.out
+}
+
+func (e *Entry) route() {
+	for _, r := range e.logger.opt.routes {
+		if !r.matches(e) {
+			continue
+		}
+		re := e
+		if len(r.Transforms) > 0 || r.Formatter != nil {
+			var err error
+			if re, err = r.transform(e); err != nil {
+				e.logger.reportError(fmt.Errorf("logie: route %s: %w", r.Name, err))
+				continue
+			}
+		}
+		if r.Sink != nil {
+			if out := r.output(e.logger); !out.enqueue(re) {
+				if err := out.outputSync(re.Context, re.Level, re.Buf.Bytes()); err != nil {
+					e.logger.reportError(fmt.Errorf("logie: route %s: %w", r.Name, err))
+				}
+			}
+		}
+		if r.Hook != nil {
+			r.callHook(re)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRouteMatches(t *testing.T) {
+	tests := []struct {
+		name  string
+		route *Route
+		log   func(l *Logger)
+		want  []string
+	}{
+		{
+			name:  "min level",
+			route: &Route{MinLevel: WarnLevel},
+			log: func(l *Logger) {
+				l.Info("info")
+				l.Error("error")
+			},
+			want: []string{"error\n"},
+		},
+		{
+			name:  "logger prefix",
+			route: &Route{Logger: "db"},
+			log: func(l *Logger) {
+				l.Info("root")
+				l.Named("db").Named("pool").Info("pool")
+				l.Named("http").Info("http")
+			},
+			want: []string{"pool logger=db.pool\n"},
+		},
+		{
+			name:  "field predicates",
+			route: &Route{Match: []func(Fields) bool{FieldEquals("code", 7), FieldPrefix("user", "adm"), FieldExists("audit")}},
+			log: func(l *Logger) {
+				l.WithFields(Fields{"code": 7, "user": "admin", "audit": true}).Info("kept")
+				l.WithFields(Fields{"code": 7, "user": "bob", "audit": true}).Info("other user")
+				l.WithFields(Fields{"code": "7", "user": "admin"}).Info("no audit")
+			},
+			want: []string{"kept audit=true code=7 user=admin\n"},
+		},
+		{
+			name:  "transformed copy",
+			route: &Route{Transforms: []Transform{RedactFields("token")}},
+			log:   func(l *Logger) { l.WithFields(Fields{"token": "s3cr3t"}).Info("login") },
+			want:  []string{"login token=[REDACTED]\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			main, sink := &collector{}, &collector{}
+			tt.route.Name, tt.route.Sink = "r", sink
+			l := New(WithPosition(main), WithFormatter(&TextFormatter{IgnoreBasicFields: true}), WithRoutes(tt.route))
+			tt.log(l)
+			if got := sink.got(); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("routed %q, want %q", got, tt.want)
+			}
+			if strings.Contains(strings.Join(main.got(), ""), "[REDACTED]") {
+				t.Errorf("transform changed the logger output: %q", main.got())
+			}
+		})
+	}
+}
+
+// blockingWriter blocks every write until release is closed.
+type blockingWriter struct {
+	collector
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return w.collector.Write(p)
+}
+
+func TestRouteSinkUsesOutputPath(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		// check runs while the sink is blocked
+		check func(t *testing.T, main *collector)
+		// wantSink entries reach the sink once it is released
+		wantSink int
+	}{
+		{
+			name:     "async",
+			opts:     []Option{WithAsync(16)},
+			check:    func(t *testing.T, main *collector) {},
+			wantSink: 2,
+		},
+		{
+			name: "watchdog",
+			opts: []Option{WithWriteWatchdog(20 * time.Millisecond), WithFallback(&collector{})},
+			check: func(t *testing.T, main *collector) {
+				if got := main.got(); len(got) != 2 {
+					t.Errorf("main output %q, want both entries", got)
+				}
+			},
+			// the second one went to the fallback
+			wantSink: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			main := &collector{}
+			sink := &blockingWriter{release: make(chan struct{})}
+			opts := append([]Option{WithPosition(main), WithFormatter(&TextFormatter{IgnoreBasicFields: true}),
+				WithRoutes(&Route{Name: "slow", Sink: sink})}, tt.opts...)
+			l := New(opts...)
+
+			done := make(chan struct{})
+			go func() {
+				l.Info("one")
+				l.Info("two")
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("a blocked route sink held back the caller")
+			}
+			tt.check(t, main)
+			close(sink.release)
+			l.Close()
+			waitFor(t, "routed entries", func() bool { return len(sink.got()) == tt.wantSink })
+		})
+	}
+}
+
+func TestRouteSinkRetries(t *testing.T) {
+	sink := &collector{fail: true}
+	l := New(WithPosition(&collector{}), WithFormatter(&TextFormatter{IgnoreBasicFields: true}),
+		WithRetry(3, 10*time.Millisecond),
+		WithRoutes(&Route{Name: "audit", Sink: sink}), WithStats())
+	defer l.Close()
+
+	l.Info("kept")
+	sink.setFail(false)
+	waitFor(t, "retried route entry", func() bool { return len(sink.got()) == 1 })
+	if st := l.Stats(); st.Sinks["route:audit"].Entries["Info"] != 1 {
+		t.Errorf("stats %+v, want the retried entry counted for route:audit", st.Sinks)
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+// WithRunbooks adds a runbook field to Error and above entries whose
+// event code, see Logger.Code, or error fingerprint, see WithFingerprint,
+// has an URL in runbooks. The code wins when both match.
+func WithRunbooks(runbooks map[string]string) Option {
+	return func(o *options) {
+		o.runbooks = runbooks
+	}
+}
+
+func (e *Entry) addRunbook() {
+	if len(e.logger.opt.runbooks) == 0 || e.Level < ErrorLevel {
+		return
+	}
+	for _, key := range []string{"code", "error.fingerprint"} {
+		id, ok := e.Fields[key].(string)
+		if !ok {
+			continue
+		}
+		if url, ok := e.logger.opt.runbooks[id]; ok {
+			e.Fields = mergeFields(e.Fields, Fields{"runbook": url})
+			return
+		}
+	}
+}
@@ -0,0 +1,455 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	errS3Closed    = errors.New("logie: s3 sink closed")
+	errS3QueueFull = errors.New("logie: s3 upload queue full")
+)
+
+const (
+	s3PartSize      = 8 << 20
+	s3MaxObjectSize = 256 << 20
+)
+
+// S3Config locates the bucket of an S3Sink, Endpoint defaults to AWS and
+// can point at any S3 compatible storage, addressed path-style.
+type S3Config struct {
+	Endpoint     string
+	Region       string
+	Bucket       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	// Prefix is prepended to the object keys.
+	Prefix string
+}
+
+type S3Option func(*S3Sink)
+
+// WithObjectSize starts a new object once the current one holds size
+// bytes before compression, objects are cut at every hour anyway.
+func WithObjectSize(size int64) S3Option {
+	return func(s *S3Sink) {
+		s.maxSize = size
+	}
+}
+
+// WithSpillDir keeps objects whose upload failed in dir, they are
+// uploaded again after the next successful upload.
+func WithSpillDir(dir string) S3Option {
+	return func(s *S3Sink) {
+		s.spillDir = dir
+	}
+}
+
+func WithS3ErrorHandler(fn func(error)) S3Option {
+	return func(s *S3Sink) {
+		s.onError = fn
+	}
+}
+
+// S3Sink archives entries into gzipped, hour-partitioned objects named
+// prefix/yyyy/mm/dd/hh/host-run-seq.json.gz, uploaded with a multipart upload
+// once larger than a part. Uploads happen in the background, objects are
+// cut when the hour changes, when they reach the object size and on Close.
+// gzip is used as zstd is not part of the standard library.
+type S3Sink struct {
+	mu       sync.Mutex
+	cfg      S3Config
+	client   *http.Client
+	host     string
+	maxSize  int64
+	spillDir string
+	onError  func(error)
+	now      func() time.Time
+
+	// run keeps the keys of successive processes apart
+	run     string
+	hour    time.Time
+	seq     int
+	raw     int64
+	buf     *bytes.Buffer
+	zw      *gzip.Writer
+	uploads chan s3Object
+	done    chan struct{}
+	wg      sync.WaitGroup
+	closing sync.Once
+	// failed holds the error of the last upload, nil once one succeeds
+	failMu sync.Mutex
+	failed error
+}
+
+type s3Object struct {
+	key  string
+	data []byte
+}
+
+func NewS3Sink(cfg S3Config, opts ...S3Option) *S3Sink {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	cfg.Endpoint = strings.TrimSuffix(cfg.Endpoint, "/")
+	host, _ := os.Hostname()
+	s := &S3Sink{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: 5 * time.Minute},
+		host:    host,
+		run:     newID()[:8],
+		maxSize: s3MaxObjectSize,
+		now:     time.Now,
+		uploads: make(chan s3Object, 16),
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.wg.Add(2)
+	go s.upload(s.uploads)
+	go s.tick()
+	return s
+}
+
+func (s *S3Sink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.uploads == nil {
+		return 0, errS3Closed
+	}
+	hour := s.now().UTC().Truncate(time.Hour)
+	if s.buf != nil && (!hour.Equal(s.hour) || s.raw >= s.maxSize) {
+		s.cut()
+	}
+	if s.buf == nil {
+		s.hour, s.buf = hour, new(bytes.Buffer)
+		s.zw = gzip.NewWriter(s.buf)
+	}
+	n, err := s.zw.Write(p)
+	s.raw += int64(n)
+	return n, err
+}
+
+// cut closes the current object and queues it, the caller holds s.mu. It
+// never waits for the uploader: with the queue full the object goes to the
+// spill directory, and is dropped without one.
+func (s *S3Sink) cut() {
+	if s.buf == nil {
+		return
+	}
+	_ = s.zw.Close()
+	s.seq++
+	key := fmt.Sprintf("%s/%s-%s-%d.json.gz", s.hour.Format("2006/01/02/15"), s.host, s.run, s.seq)
+	if s.cfg.Prefix != "" {
+		key = strings.TrimSuffix(s.cfg.Prefix, "/") + "/" + key
+	}
+	obj := s3Object{key: key, data: s.buf.Bytes()}
+	s.buf, s.zw, s.raw = nil, nil, 0
+	select {
+	case s.uploads <- obj:
+		return
+	default:
+	}
+	err := errS3QueueFull
+	if s.spillDir != "" {
+		if err = s.spill(obj); err != nil {
+			err = fmt.Errorf("%w (spill: %v)", errS3QueueFull, err)
+		}
+	}
+	if err != nil && s.onError != nil {
+		s.onError(err)
+	}
+}
+
+func (s *S3Sink) tick() {
+	defer s.wg.Done()
+	t := time.NewTicker(time.Minute)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			s.mu.Lock()
+			if s.buf != nil && !s.now().UTC().Truncate(time.Hour).Equal(s.hour) {
+				s.cut()
+			}
+			s.mu.Unlock()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *S3Sink) upload(uploads <-chan s3Object) {
+	defer s.wg.Done()
+	for obj := range uploads {
+		err := s.put(obj.key, obj.data)
+		s.failMu.Lock()
+		s.failed = err
+		s.failMu.Unlock()
+		if err == nil {
+			s.retrySpilled()
+			continue
+		}
+		if s.spillDir != "" {
+			if serr := s.spill(obj); serr != nil {
+				err = fmt.Errorf("%w (spill: %v)", err, serr)
+			}
+		}
+		if s.onError != nil {
+			s.onError(err)
+		}
+	}
+	// objects spilled while the queue was full get a last chance
+	s.retrySpilled()
+}
+
+func (s *S3Sink) spill(obj s3Object) error {
+	if err := os.MkdirAll(s.spillDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.spillDir, url.PathEscape(obj.key)), obj.data, 0644)
+}
+
+func (s *S3Sink) retrySpilled() {
+	if s.spillDir == "" {
+		return
+	}
+	entries, err := os.ReadDir(s.spillDir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		key, err := url.PathUnescape(e.Name())
+		if err != nil || e.IsDir() {
+			continue
+		}
+		path := filepath.Join(s.spillDir, e.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if err := s.put(key, data); err != nil {
+			return
+		}
+		_ = os.Remove(path)
+	}
+}
+
+// Healthy reports whether the sink is closed or its last upload failed.
+func (s *S3Sink) Healthy() error {
+	s.mu.Lock()
+	closed := s.uploads == nil
+	s.mu.Unlock()
+	if closed {
+		return errS3Closed
+	}
+	s.failMu.Lock()
+	defer s.failMu.Unlock()
+	if s.failed != nil {
+		return fmt.Errorf("logie: last s3 upload failed: %w", s.failed)
+	}
+	return nil
+}
+
+// Close uploads the current object and waits for pending uploads.
+func (s *S3Sink) Close() error {
+	s.closing.Do(func() {
+		close(s.done)
+		s.mu.Lock()
+		s.cut()
+		close(s.uploads)
+		s.uploads = nil
+		s.mu.Unlock()
+		s.wg.Wait()
+	})
+	return nil
+}
+
+// put stores data under key, with a multipart upload above one part.
+func (s *S3Sink) put(key string, data []byte) error {
+	if len(data) <= s3PartSize {
+		_, err := s.do(http.MethodPut, key, nil, data)
+		return err
+	}
+
+	resp, err := s.do(http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return err
+	}
+	var initiate struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.Unmarshal(resp, &initiate); err != nil {
+		return err
+	}
+
+	type part struct {
+		Number int    `xml:"PartNumber"`
+		ETag   string `xml:"ETag"`
+	}
+	var parts []part
+	for n, off := 1, 0; off < len(data); n, off = n+1, off+s3PartSize {
+		end := off + s3PartSize
+		if end > len(data) {
+			end = len(data)
+		}
+		etag, err := s.uploadPart(key, initiate.UploadID, n, data[off:end])
+		if err != nil {
+			_, _ = s.do(http.MethodDelete, key, url.Values{"uploadId": {initiate.UploadID}}, nil)
+			return err
+		}
+		parts = append(parts, part{Number: n, ETag: etag})
+	}
+
+	complete, _ := xml.Marshal(struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []part   `xml:"Part"`
+	}{Parts: parts})
+	_, err = s.do(http.MethodPost, key, url.Values{"uploadId": {initiate.UploadID}}, complete)
+	return err
+}
+
+func (s *S3Sink) uploadPart(key, uploadID string, n int, data []byte) (string, error) {
+	req, err := s.request(http.MethodPut, key, url.Values{"partNumber": {strconv.Itoa(n)}, "uploadId": {uploadID}}, data)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", &httpStatusError{status: resp.StatusCode, body: readLimited(resp.Body, 512)}
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+func (s *S3Sink) do(method, key string, query url.Values, body []byte) ([]byte, error) {
+	req, err := s.request(method, key, query, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, &httpStatusError{status: resp.StatusCode, body: readLimited(resp.Body, 512)}
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// request builds a signed request for key.
+func (s *S3Sink) request(method, key string, query url.Values, body []byte) (*http.Request, error) {
+	path := "/" + s.cfg.Bucket + "/" + s3EscapePath(key)
+	rawQuery := s3CanonicalQuery(query)
+	req, err := http.NewRequest(method, s.cfg.Endpoint+path+"?"+rawQuery, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+
+	s.sign(req, path, rawQuery, body)
+	return req, nil
+}
+
+// sign adds the AWS Signature Version 4 headers to req.
+func (s *S3Sink) sign(req *http.Request, path, rawQuery string, body []byte) {
+	method := req.Method
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.cfg.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k := range req.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-") || lk == "content-type" {
+			headers[lk] = strings.TrimSpace(req.Header.Get(k))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{method, path, rawQuery, canonicalHeaders.String(), signed, payloadHash}, "\n")
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	crSum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(crSum[:])
+
+	k := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), date)
+	k = hmacSHA256(k, s.cfg.Region)
+	k = hmacSHA256(k, "s3")
+	k = hmacSHA256(k, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(k, toSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.cfg.AccessKey+"/"+scope+
+		", SignedHeaders="+signed+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func s3EscapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, seg := range segments {
+		segments[i] = s3Escape(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+func s3CanonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		parts = append(parts, s3Escape(k)+"="+s3Escape(q.Get(k)))
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes everything but the unreserved characters of
+// RFC 3986, as SigV4 requires.
+func s3Escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// s3Server stores the objects PUT to it, failing while fail is set.
+type s3Server struct {
+	mu      sync.Mutex
+	objects map[string]string
+	auth    []string
+	fail    bool
+}
+
+func (s *s3Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	data, _ := io.ReadAll(zr)
+	s.objects[r.URL.Path] = string(data)
+	s.auth = append(s.auth, r.Header.Get("Authorization"))
+}
+
+func (s *s3Server) setFail(fail bool) {
+	s.mu.Lock()
+	s.fail = fail
+	s.mu.Unlock()
+}
+
+func (s *s3Server) got() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]string, len(s.objects))
+	for k, v := range s.objects {
+		out[k] = v
+	}
+	return out
+}
+
+func newS3Test(t *testing.T, opts ...S3Option) (*s3Server, *S3Sink, *time.Time) {
+	t.Helper()
+	store := &s3Server{objects: map[string]string{}}
+	srv := httptest.NewServer(store)
+	t.Cleanup(srv.Close)
+	now := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	s := NewS3Sink(S3Config{Endpoint: srv.URL, Region: "eu-west-1", Bucket: "logs", AccessKey: "AK", SecretKey: "SK", Prefix: "app/"}, opts...)
+	s.now = func() time.Time { return now }
+	return store, s, &now
+}
+
+func TestS3SinkObjects(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []S3Option
+		write func(s *S3Sink, now *time.Time)
+		want  []string
+	}{
+		{
+			name: "one object",
+			write: func(s *S3Sink, now *time.Time) {
+				s.Write([]byte("a\n"))
+				s.Write([]byte("b\n"))
+			},
+			want: []string{"a\nb\n"},
+		},
+		{
+			name: "cut at the hour",
+			write: func(s *S3Sink, now *time.Time) {
+				s.Write([]byte("a\n"))
+				*now = now.Add(time.Hour)
+				s.Write([]byte("b\n"))
+			},
+			want: []string{"a\n", "b\n"},
+		},
+		{
+			name: "cut at the object size",
+			opts: []S3Option{WithObjectSize(2)},
+			write: func(s *S3Sink, now *time.Time) {
+				s.Write([]byte("a\n"))
+				s.Write([]byte("b\n"))
+			},
+			want: []string{"a\n", "b\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, s, now := newS3Test(t, tt.opts...)
+			tt.write(s, now)
+			s.Close()
+			got := store.got()
+			if len(got) != len(tt.want) {
+				t.Fatalf("objects %q, want %q", got, tt.want)
+			}
+			for key, data := range got {
+				if !strings.HasPrefix(key, "/logs/app/2026/03/04/0") || !strings.HasSuffix(key, ".json.gz") {
+					t.Errorf("key %s, want the bucket, prefix and hour", key)
+				}
+				found := false
+				for _, w := range tt.want {
+					found = found || data == w
+				}
+				if !found {
+					t.Errorf("object %s = %q, want one of %q", key, data, tt.want)
+				}
+			}
+			for _, a := range store.auth {
+				if !strings.HasPrefix(a, "AWS4-HMAC-SHA256 Credential=AK/20260304/eu-west-1/s3/aws4_request") {
+					t.Errorf("Authorization = %q", a)
+				}
+			}
+		})
+	}
+}
+
+func TestS3SinkSpill(t *testing.T) {
+	dir := t.TempDir()
+	store, s, now := newS3Test(t, WithSpillDir(dir), WithS3ErrorHandler(func(error) {}))
+	store.setFail(true)
+	s.Write([]byte("a\n"))
+	*now = now.Add(time.Hour)
+	s.Write([]byte("b\n"))
+	waitFor(t, "spill", func() bool {
+		entries, _ := os.ReadDir(dir)
+		return len(entries) == 1
+	})
+
+	store.setFail(false)
+	s.Close()
+	if got := store.got(); len(got) != 2 {
+		t.Errorf("objects %q, want the spilled one uploaded again", got)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("spill directory keeps %d objects", len(entries))
+	}
+}
+
+func TestS3SinkConcurrentClose(t *testing.T) {
+	store, s, _ := newS3Test(t)
+	s.Write([]byte("a\n"))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Close()
+		}()
+	}
+	wg.Wait()
+	if got := store.got(); len(got) != 1 {
+		t.Errorf("objects %q, want one", got)
+	}
+	if _, err := s.Write([]byte("b\n")); err != errS3Closed {
+		t.Errorf("Write() after Close error = %v, want errS3Closed", err)
+	}
+}
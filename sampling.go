@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+type sampler struct {
+	mu         sync.Mutex
+	first      uint64
+	thereafter uint64
+	tick       time.Duration
+	reset      time.Time
+	counts     map[sampleKey]*uint64
+}
+
+type sampleKey struct {
+	lvl Level
+	msg string
+}
+
+// WithSampling logs the first entries with the same level and message in
+// every tick, then only one in thereafter of them.
+func WithSampling(first, thereafter int, tick time.Duration) Option {
+	return func(o *options) {
+		o.sampler = &sampler{
+			first:      uint64(first),
+			thereafter: uint64(thereafter),
+			tick:       tick,
+			counts:     make(map[sampleKey]*uint64),
+		}
+	}
+}
+
+func (s *sampler) allow(now time.Time, lvl Level, format string, args []any) bool {
+	key := sampleKey{lvl: lvl, msg: messageKey(format, args)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.reset) >= s.tick {
+		s.reset = now
+		for k := range s.counts {
+			delete(s.counts, k)
+		}
+	}
+	count, ok := s.counts[key]
+	if !ok {
+		// msg may alias bytes of a zero-copy Write, keep a copy
+		key.msg = string([]byte(key.msg))
+		count = new(uint64)
+		s.counts[key] = count
+	}
+	*count++
+	n := *count
+	if n <= s.first {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.first)%s.thereafter == 0
+}
+
+// messageKey identifies the log statement of an entry: its format, or
+// the first argument of a Print-style call.
+func messageKey(format string, args []any) string {
+	if format != FmtEmptySeparate || len(args) == 0 {
+		return format
+	}
+	if msg, ok := args[0].(string); ok {
+		return msg
+	}
+	return fmt.Sprintf("%T", args[0])
+}
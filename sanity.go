@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// SanityCheck selects the configuration problems New warns about.
+type SanityCheck uint8
+
+const (
+	// CheckJSONOnTTY warns when JSON is written to a terminal.
+	CheckJSONOnTTY SanityCheck = 1 << iota
+	// CheckProductionCaller warns when NewProduction runs with callers
+	// re-enabled.
+	CheckProductionCaller
+	// CheckNetworkFS warns when the output file lives on NFS or SMB.
+	CheckNetworkFS
+	// CheckGOMAXPROCS warns when GOMAXPROCS exceeds the cgroup CPU quota.
+	CheckGOMAXPROCS
+
+	AllSanityChecks = CheckJSONOnTTY | CheckProductionCaller | CheckNetworkFS | CheckGOMAXPROCS
+)
+
+// WithSanityChecks makes New log a warning for each detected problem
+// among checks, regardless of the configured level.
+func WithSanityChecks(checks SanityCheck) Option {
+	return func(o *options) {
+		o.sanity = checks
+	}
+}
+
+func (l *Logger) checkSanity() {
+	warn := func(check, msg string, fields Fields) {
+		fields["check"] = check
+		l.derive(func(o *options) {
+			o.level, o.disableCaller = TraceLevel, true
+		}).WithFields(fields).entry().write(WarnLevel, FmtEmptySeparate, "logie: "+msg)
+	}
+
+	checks := l.opt.sanity
+	if checks&CheckJSONOnTTY != 0 {
+		if _, ok := l.opt.formatter.(*JSONFormatter); ok && isTerminal(l.opt.position) {
+			warn("json_on_tty", "JSON output written to a terminal", Fields{})
+		}
+	}
+	if checks&CheckProductionCaller != 0 && l.opt.production && !l.opt.disableCaller {
+		warn("production_caller", "caller enabled in the production preset", Fields{})
+	}
+	if checks&CheckNetworkFS != 0 {
+		if path := outputPath(l.opt.position); path != "" {
+			if fs, ok := networkFS(path); ok {
+				warn("network_fs", "output file on a network file system", Fields{"path": path, "fs": fs})
+			}
+		}
+	}
+	if checks&CheckGOMAXPROCS != 0 {
+		if quota, ok := cgroupCPUs(); ok && float64(runtime.GOMAXPROCS(0)) > quota+0.999 {
+			warn("gomaxprocs", "GOMAXPROCS exceeds the cgroup CPU quota", Fields{
+				"gomaxprocs": runtime.GOMAXPROCS(0),
+				"cpu_quota":  quota,
+			})
+		}
+	}
+}
+
+func isTerminal(w interface{}) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	st, err := f.Stat()
+	return err == nil && st.Mode()&os.ModeCharDevice != 0
+}
+
+func outputPath(w interface{}) string {
+	switch out := w.(type) {
+	case *FileWriter:
+		return out.Path()
+	case *os.File:
+		if st, err := out.Stat(); err == nil && st.Mode().IsRegular() {
+			return out.Name()
+		}
+	}
+	return ""
+}
+
+// cgroupCPUs returns the CPU quota of the cgroup of the process, it is
+// false when there is none.
+func cgroupCPUs() (float64, bool) {
+	if b, err := os.ReadFile("/sys/fs/cgroup/cpu.max"); err == nil {
+		parts := strings.Fields(string(b))
+		if len(parts) == 2 && parts[0] != "max" {
+			return ratio(parts[0], parts[1])
+		}
+		return 0, false
+	}
+	q, err1 := os.ReadFile("/sys/fs/cgroup/cpu/cpu.cfs_quota_us")
+	p, err2 := os.ReadFile("/sys/fs/cgroup/cpu/cpu.cfs_period_us")
+	if err1 != nil || err2 != nil {
+		return 0, false
+	}
+	return ratio(strings.TrimSpace(string(q)), strings.TrimSpace(string(p)))
+}
+
+func ratio(quota, period string) (float64, bool) {
+	q, err1 := strconv.ParseFloat(quota, 64)
+	p, err2 := strconv.ParseFloat(period, 64)
+	if err1 != nil || err2 != nil || q <= 0 || p <= 0 {
+		return 0, false
+	}
+	return q / p, true
+}
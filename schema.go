@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// SchemaVersion is emitted as schema_version by JSONFormatter, it is
+// bumped whenever a basic field or a field added by an option is added,
+// renamed or removed.
+const SchemaVersion = "3"
+
+// optionKeys are the string fields added by Named, Code, WithFingerprint,
+// WithRunbooks, WithMessageCatalog, WithPartitionFields and the context
+// helpers. They are not reserved, the options add them as user fields,
+// but their type is part of the schema.
+var optionKeys = []string{"logger", "code", "code_url", "error.fingerprint", "runbook", "msg_id", "date", "hour", "week", "ctx_err"}
+
+const jsonSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "https://github.com/i0Ek3/logie/schema/v3.json",
+  "title": "logie entry",
+  "type": "object",
+  "required": ["schema_version", "level", "time", "message"],
+  "properties": {
+    "schema_version": {"const": "3"},
+    "level": {"enum": ["Trace", "Debug", "Info", "Warn", "Error", "Panic", "Fatal"]},
+    "time": {"type": "string", "format": "date-time"},
+    "file": {"type": "string"},
+    "func": {"type": "string"},
+    "message": {"type": "string"},
+    "errors": {"type": "array", "items": {"type": "string"}},
+    "logger": {"type": "string"},
+    "code": {"type": "string"},
+    "code_url": {"type": "string", "format": "uri"},
+    "error.fingerprint": {"type": "string", "pattern": "^[0-9a-f]{16}$"},
+    "runbook": {"type": "string", "format": "uri"},
+    "msg_id": {"type": "string"},
+    "date": {"type": "string", "format": "date"},
+    "hour": {"type": "string", "pattern": "^[0-9]{2}$"},
+    "week": {"type": "string", "pattern": "^[0-9]{4}-W[0-9]{2}$"},
+    "ctx_err": {"type": "string"}
+  }
+}
+`
+
+// JSONSchema returns the JSON Schema describing entries written by
+// JSONFormatter with basic fields enabled.
+func JSONSchema() []byte {
+	return []byte(jsonSchema)
+}
+
+// ValidateJSON checks that line is a single entry conforming to
+// JSONSchema, it is meant to be used from tests of downstream parsers.
+func ValidateJSON(line []byte) error {
+	var m map[string]any
+	if err := decodeJSON(line, &m); err != nil {
+		return fmt.Errorf("logie: invalid json entry: %w", err)
+	}
+
+	for _, key := range []string{"schema_version", "level", "time", "message"} {
+		if _, ok := m[key]; !ok {
+			return fmt.Errorf("logie: missing required field %q", key)
+		}
+	}
+	for _, key := range append([]string{"schema_version", "level", "time", "message", "file", "func"}, optionKeys...) {
+		if v, ok := m[key]; ok {
+			if _, ok := v.(string); !ok {
+				return fmt.Errorf("logie: field %q must be a string", key)
+			}
+		}
+	}
+
+	if v, ok := m["errors"]; ok {
+		errs, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("logie: field %q must be an array", "errors")
+		}
+		for _, msg := range errs {
+			if _, ok := msg.(string); !ok {
+				return fmt.Errorf("logie: field %q must hold strings", "errors")
+			}
+		}
+	}
+
+	if v := m["schema_version"].(string); v != SchemaVersion {
+		return fmt.Errorf("logie: unsupported schema_version %q", v)
+	}
+	var lvl Level
+	if err := lvl.UnmarshalText([]byte(m["level"].(string))); err != nil {
+		return fmt.Errorf("logie: %w", err)
+	}
+	if _, err := time.Parse(time.RFC3339, m["time"].(string)); err != nil {
+		return fmt.Errorf("logie: invalid time: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONSchemaVersion(t *testing.T) {
+	var schema struct {
+		ID         string `json:"$id"`
+		Properties map[string]struct {
+			Const string `json:"const"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(JSONSchema(), &schema); err != nil {
+		t.Fatal(err)
+	}
+	if got := schema.Properties["schema_version"].Const; got != SchemaVersion {
+		t.Errorf("schema_version const %q, want %q", got, SchemaVersion)
+	}
+	if !strings.HasSuffix(schema.ID, "/v"+SchemaVersion+".json") {
+		t.Errorf("$id %s does not carry version %s", schema.ID, SchemaVersion)
+	}
+	for _, key := range optionKeys {
+		if _, ok := schema.Properties[key]; !ok {
+			t.Errorf("field %s added by an option is not declared", key)
+		}
+	}
+}
+
+func TestValidateJSONOptionFields(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(WithPosition(&buf), WithFormatter(&JSONFormatter{}), WithFingerprint(),
+		WithPartitionFields(PartitionDate|PartitionHour|PartitionWeek),
+		WithRunbooks(map[string]string{"DB001": "https://runbooks.example/db"}),
+		WithMessageCatalog(func(id string) (string, bool) { return "translated", true }))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.Named("db").Code("DB001").ErrorCtx(ctx, "query failed")
+
+	line := bytes.TrimSpace(buf.Bytes())
+	if err := ValidateJSON(line); err != nil {
+		t.Fatalf("ValidateJSON(%s): %v", line, err)
+	}
+	for _, key := range []string{"logger", "code", "error.fingerprint", "runbook", "msg_id", "date", "hour", "week", "ctx_err"} {
+		if !bytes.Contains(line, []byte(`"`+key+`":`)) {
+			t.Errorf("entry %s has no %s", line, key)
+		}
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		wantErr string
+	}{
+		{"valid", `{"schema_version":"3","level":"Info","time":"2024-05-01T10:00:00Z","message":"hi"}`, ""},
+		{"old version", `{"schema_version":"2","level":"Info","time":"2024-05-01T10:00:00Z","message":"hi"}`, "unsupported schema_version"},
+		{"missing message", `{"schema_version":"3","level":"Info","time":"2024-05-01T10:00:00Z"}`, "missing required field"},
+		{"numeric code", `{"schema_version":"3","level":"Info","time":"2024-05-01T10:00:00Z","message":"hi","code":7}`, `"code" must be a string`},
+		{"bad level", `{"schema_version":"3","level":"Loud","time":"2024-05-01T10:00:00Z","message":"hi"}`, "level"},
+		{"errors not strings", `{"schema_version":"3","level":"Info","time":"2024-05-01T10:00:00Z","message":"hi","errors":[1]}`, "must hold strings"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateJSON([]byte(tt.line))
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("ValidateJSON() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
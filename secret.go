@@ -0,0 +1,33 @@
+package main
+
+import "fmt"
+
+// Secret holds a sensitive string that every formatter masks: it prints
+// and encodes as [REDACTED] whatever the verb or encoder. Reveal returns
+// the value.
+type Secret string
+
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return redacted
+}
+
+// Format masks s for every fmt verb, %x and %q included.
+func (s Secret) Format(f fmt.State, verb rune) {
+	_, _ = f.Write([]byte(redacted))
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + redacted + `"`), nil
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(redacted), nil
+}
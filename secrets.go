@@ -0,0 +1,144 @@
+package main
+
+import (
+	"regexp"
+	"sync/atomic"
+)
+
+// SecretDetector finds one shape of secret in formatted entries. Match,
+// when set, confirms a candidate found by Pattern. Group selects the
+// submatch to redact, zero redacts the whole match.
+type SecretDetector struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Group   int
+	Match   func(candidate []byte) bool
+}
+
+var defaultSecretDetectors = []SecretDetector{
+	{Name: "aws_access_key", Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "jwt", Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)},
+	{Name: "bearer", Pattern: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`), Group: 1},
+	{Name: "credit_card", Pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), Match: luhn},
+}
+
+func DefaultSecretDetectors() []SecretDetector {
+	return append([]SecretDetector(nil), defaultSecretDetectors...)
+}
+
+type secretScanner struct {
+	detectors []SecretDetector
+	redacted  uint64
+}
+
+// WithSecretScan redacts secrets found by detectors, or by the default
+// detectors when none are given, from every entry before it is written.
+func WithSecretScan(detectors ...SecretDetector) Option {
+	if len(detectors) == 0 {
+		detectors = defaultSecretDetectors
+	}
+	return func(o *options) {
+		o.secrets = &secretScanner{detectors: detectors}
+	}
+}
+
+// SecretsRedacted returns the number of secrets removed by WithSecretScan.
+func (l *Logger) SecretsRedacted() uint64 {
+	if s := l.opt.secrets; s != nil {
+		return atomic.LoadUint64(&s.redacted)
+	}
+	return 0
+}
+
+func (s *secretScanner) scan(p []byte) []byte {
+	for _, d := range s.detectors {
+		matches := d.Pattern.FindAllSubmatchIndex(p, -1)
+		if len(matches) == 0 {
+			continue
+		}
+
+		out := make([]byte, 0, len(p))
+		last := 0
+		var js jsonScan
+		for _, m := range matches {
+			start, end := m[2*d.Group], m[2*d.Group+1]
+			if start < 0 || (d.Match != nil && !d.Match(p[start:end])) {
+				continue
+			}
+			out = append(out, p[last:start]...)
+			if js.bareValue(p, start) {
+				// a JSON number or literal, keep the document valid
+				out = append(out, '"')
+				out = append(out, redacted...)
+				out = append(out, '"')
+			} else {
+				out = append(out, redacted...)
+			}
+			last = end
+			atomic.AddUint64(&s.redacted, 1)
+		}
+		p = append(out, p[last:]...)
+	}
+	return p
+}
+
+// jsonScan follows the strings of a formatted entry from left to right.
+type jsonScan struct {
+	pos      int
+	inString bool
+}
+
+// bareValue reports whether off, at or after the previous call, is outside
+// any string and at the start of a JSON value.
+func (s *jsonScan) bareValue(p []byte, off int) bool {
+	for ; s.pos < off; s.pos++ {
+		switch c := p[s.pos]; {
+		case c == '\\' && s.inString:
+			s.pos++
+		case c == '"':
+			s.inString = !s.inString
+		}
+	}
+	if s.inString {
+		return false
+	}
+	for i := off - 1; i >= 0; i-- {
+		switch p[i] {
+		case ' ', '\t':
+			continue
+		case ':', ',', '[':
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+func (e *Entry) scanSecrets() {
+	s := e.logger.opt.secrets
+	if s == nil {
+		return
+	}
+	out := s.scan(e.Buf.Bytes())
+	e.Buf.Reset()
+	e.Buf.Write(out)
+}
+
+func luhn(candidate []byte) bool {
+	var sum, n int
+	for i := len(candidate) - 1; i >= 0; i-- {
+		c := candidate[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
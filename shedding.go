@@ -0,0 +1,93 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// latencyStale is how long a write latency sample counts as pressure
+// without newer writes.
+const latencyStale = time.Second
+
+type shedder struct {
+	level      Level
+	highWater  float64
+	maxLatency time.Duration
+	active     int32
+	// latency is a moving average of write durations in nanoseconds,
+	// sampled at the Unix nanosecond time sampled
+	latency int64
+	sampled int64
+}
+
+// WithLoadShedding drops entries below lvl while the logger is under
+// pressure: the async queue is filled above highWater, a fraction of its
+// size, or the average write takes longer than maxLatency. Zero disables
+// either check. The configured levels are restored once both fall below
+// half their limit, each transition is logged.
+func WithLoadShedding(lvl Level, highWater float64, maxLatency time.Duration) Option {
+	return func(o *options) {
+		o.shed = &shedder{level: lvl, highWater: highWater, maxLatency: maxLatency}
+	}
+}
+
+// Shedding reports whether WithLoadShedding currently raises the level.
+func (l *Logger) Shedding() bool {
+	s := l.opt.shed
+	return s != nil && atomic.LoadInt32(&s.active) == 1
+}
+
+func (s *shedder) observe(d time.Duration) {
+	avg := atomic.LoadInt64(&s.latency)
+	atomic.StoreInt64(&s.latency, avg+(int64(d)-avg)/8)
+	atomic.StoreInt64(&s.sampled, time.Now().UnixNano())
+}
+
+// shedLevel returns lvl raised to the shedding level under pressure.
+func (l *Logger) shedLevel(lvl Level) Level {
+	s := l.opt.shed
+	if s == nil {
+		return lvl
+	}
+	fill := l.queueFill()
+	latency := time.Duration(atomic.LoadInt64(&s.latency))
+	if time.Since(time.Unix(0, atomic.LoadInt64(&s.sampled))) > latencyStale {
+		latency = 0
+	}
+
+	pressure := s.highWater > 0 && fill >= s.highWater ||
+		s.maxLatency > 0 && latency > s.maxLatency
+	relief := (s.highWater <= 0 || fill < s.highWater/2) &&
+		(s.maxLatency <= 0 || latency < s.maxLatency/2)
+	switch {
+	case pressure && atomic.CompareAndSwapInt32(&s.active, 0, 1):
+		l.shedTransition(WarnLevel, "load shedding started", fill, latency)
+	case relief && atomic.CompareAndSwapInt32(&s.active, 1, 0):
+		l.shedTransition(InfoLevel, "load shedding stopped", fill, latency)
+	}
+
+	if atomic.LoadInt32(&s.active) == 1 && lvl < s.level {
+		return s.level
+	}
+	return lvl
+}
+
+func (l *Logger) queueFill() float64 {
+	a := l.opt.async
+	if a == nil {
+		return 0
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.ch == nil || cap(a.ch) == 0 {
+		return 0
+	}
+	return float64(len(a.ch)) / float64(cap(a.ch))
+}
+
+func (l *Logger) shedTransition(lvl Level, msg string, fill float64, latency time.Duration) {
+	fields := Fields{"shed_level": LevelMapping[l.opt.shed.level], "queue_fill": fill, "write_latency": latency.String()}
+	l.derive(func(o *options) {
+		o.level, o.shed, o.disableCaller = TraceLevel, nil, true
+	}).WithFields(fields).entry().write(lvl, FmtEmptySeparate, msg)
+}
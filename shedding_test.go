@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowWriter delays every write by delay nanoseconds.
+type slowWriter struct {
+	collector
+	delay int64
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(time.Duration(atomic.LoadInt64(&w.delay)))
+	return w.collector.Write(p)
+}
+
+func TestLoadSheddingLatency(t *testing.T) {
+	tests := []struct {
+		name string
+		info func(l *Logger, msg string)
+	}{
+		{name: "plain", info: func(l *Logger, msg string) { l.Info(msg) }},
+		{name: "context", info: func(l *Logger, msg string) { l.InfoCtx(context.Background(), msg) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &slowWriter{delay: int64(40 * time.Millisecond)}
+			l := New(WithPosition(out), WithFormatter(&TextFormatter{IgnoreBasicFields: true}),
+				WithLoadShedding(WarnLevel, 0, time.Millisecond))
+
+			tt.info(l, "slow")
+			tt.info(l, "dropped")
+			if !l.Shedding() {
+				t.Fatal("Shedding() = false after a slow write")
+			}
+			l.Error("kept")
+
+			atomic.StoreInt64(&out.delay, 0)
+			for i := 0; l.Shedding() && i < 100; i++ {
+				l.Warn("drain")
+			}
+			tt.info(l, "back")
+
+			var got []string
+			for _, e := range out.got() {
+				if e != "drain\n" {
+					got = append(got, e[:strings.IndexAny(e, " \n")])
+				}
+			}
+			want := []string{"slow", "load", "kept", "load", "back"}
+			if strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Errorf("logged %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadSheddingQueue(t *testing.T) {
+	out := &blockingWriter{release: make(chan struct{})}
+	l := New(WithPosition(out), WithFormatter(&TextFormatter{IgnoreBasicFields: true}),
+		WithAsync(4), WithLoadShedding(ErrorLevel, 0.5, 0))
+	// the first entry blocks the writer, the next ones fill the queue
+	for i := 0; i < 4; i++ {
+		l.Info("fill")
+	}
+	if !l.Shedding() {
+		t.Fatal("Shedding() = false with the queue above the high water mark")
+	}
+	if l.enabled(WarnLevel) {
+		t.Error("Warn enabled while shedding below Error")
+	}
+	close(out.release)
+	l.Close()
+	if got := out.got(); !strings.Contains(strings.Join(got, ""), "load shedding started") {
+		t.Errorf("logged %q, want the start of shedding", got)
+	}
+}
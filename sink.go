@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SinkFactory builds an output from a parsed sink URL.
+type SinkFactory func(u *url.URL) (io.Writer, error)
+
+var (
+	sinksMu sync.RWMutex
+	sinks   = map[string]SinkFactory{
+		"stderr":   func(*url.URL) (io.Writer, error) { return os.Stderr, nil },
+		"stdout":   func(*url.URL) (io.Writer, error) { return os.Stdout, nil },
+		"file":     openFileSink,
+		"tcp":      openNetSink,
+		"udp":      openNetSink,
+		"tls":      openTLSSink,
+		"http":     openHTTPSink,
+		"https":    openHTTPSink,
+		"nats":     openNATSSink,
+		"redis":    openRedisSink,
+		"rediss":   openRedisSink,
+		"mqtt":     openMQTTSink,
+		"mqtts":    openMQTTSink,
+		"unix":     openUnixSink,
+		"unixgram": openUnixSink,
+		"npipe":    openPipeSink,
+	}
+)
+
+// RegisterSink makes outputs with the given URL scheme available to
+// OpenSink, registering a scheme twice replaces the previous factory.
+func RegisterSink(scheme string, factory SinkFactory) {
+	sinksMu.Lock()
+	sinks[strings.ToLower(scheme)] = factory
+	sinksMu.Unlock()
+}
+
+// OpenSink builds an output from a URL such as "stderr:",
+// "file:///var/log/app.log?rotate=100MB", "tcp://collector:514" or
+// "tls://collector:6514?ca=/etc/ca.pem". The prefix and suffix query
+// parameters decorate the lines of any sink, see Decorate.
+func OpenSink(rawURL string) (io.Writer, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("logie: invalid sink %q: %w", rawURL, err)
+	}
+	sinksMu.RLock()
+	factory, ok := sinks[strings.ToLower(u.Scheme)]
+	sinksMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("logie: no sink registered for scheme %q", u.Scheme)
+	}
+	w, err := factory(u)
+	if err != nil {
+		return nil, err
+	}
+	if q := u.Query(); q.Get("prefix") != "" || q.Get("suffix") != "" {
+		w = Decorate(w, q.Get("prefix"), q.Get("suffix"))
+	}
+	return w, nil
+}
+
+// openFileSink understands the rotate (size), symlink and copytruncate
+// query parameters.
+func openFileSink(u *url.URL) (io.Writer, error) {
+	path := u.Path
+	if u.Opaque != "" {
+		path = u.Opaque
+	}
+	if path == "" {
+		return nil, fmt.Errorf("logie: file sink %q has no path", u.String())
+	}
+
+	var opts []FileOption
+	q := u.Query()
+	if v := q.Get("rotate"); v != "" {
+		size, err := parseSize(v)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithMaxSize(size))
+	}
+	if q.Has("symlink") {
+		opts = append(opts, WithSymlink())
+	}
+	if q.Has("copytruncate") {
+		opts = append(opts, WithCopyTruncate())
+	}
+	return NewFileWriter(path, opts...)
+}
+
+func openNetSink(u *url.URL) (io.Writer, error) {
+	return net.Dial(u.Scheme, u.Host)
+}
+
+// parseSize parses sizes like 512, 64KB, 100MB or 1GB.
+func parseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		mult   int64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"B", 1}}
+
+	num, mult := strings.ToUpper(strings.TrimSpace(s)), int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(num, u.suffix) {
+			num, mult = strings.TrimSpace(strings.TrimSuffix(num, u.suffix)), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("logie: invalid size %q", s)
+	}
+	return n * mult, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SocketWriter writes to a local endpoint exposed by a host agent, a Unix
+// stream or datagram socket or a Windows named pipe. A failed write
+// closes the connection and is retried once on a new one, later writes
+// keep reconnecting, at most once per second.
+type SocketWriter struct {
+	mu       sync.Mutex
+	dial     func() (io.WriteCloser, error)
+	conn     io.WriteCloser
+	lastDial time.Time
+}
+
+// NewUnixWriter connects to the Unix socket at path, network is "unix"
+// for stream or "unixgram" for datagram sockets, where every entry is
+// sent as one datagram.
+func NewUnixWriter(network, path string) (*SocketWriter, error) {
+	return newSocketWriter(func() (io.WriteCloser, error) {
+		return net.DialTimeout(network, path, 5*time.Second)
+	})
+}
+
+// NewPipeWriter opens the Windows named pipe at path, such as
+// `\\.\pipe\agent`.
+func NewPipeWriter(path string) (*SocketWriter, error) {
+	return newSocketWriter(func() (io.WriteCloser, error) {
+		return os.OpenFile(path, os.O_WRONLY, 0)
+	})
+}
+
+func newSocketWriter(dial func() (io.WriteCloser, error)) (*SocketWriter, error) {
+	conn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	return &SocketWriter{dial: dial, conn: conn, lastDial: time.Now()}, nil
+}
+
+func (w *SocketWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		if time.Since(w.lastDial) < time.Second {
+			return 0, io.ErrClosedPipe
+		}
+		if err := w.redial(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.conn.Write(p)
+	if err == nil {
+		return n, nil
+	}
+	w.conn.Close()
+	w.conn = nil
+	if err := w.redial(); err != nil {
+		return 0, err
+	}
+	return w.conn.Write(p)
+}
+
+// redial connects again, the caller holds w.mu.
+func (w *SocketWriter) redial() error {
+	w.lastDial = time.Now()
+	conn, err := w.dial()
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+func (w *SocketWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func openUnixSink(u *url.URL) (io.Writer, error) {
+	path := u.Path
+	if u.Opaque != "" {
+		path = u.Opaque
+	}
+	return NewUnixWriter(u.Scheme, path)
+}
+
+// openPipeSink maps "npipe://./pipe/agent" to `\\.\pipe\agent`.
+func openPipeSink(u *url.URL) (io.Writer, error) {
+	host := u.Host
+	if host == "" {
+		host = "."
+	}
+	return NewPipeWriter(`\\` + host + strings.ReplaceAll(u.Path, "/", `\`))
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type SplunkOption func(*splunkConfig)
+
+type splunkConfig struct {
+	sourcetype string
+	index      string
+	source     string
+	host       string
+	raw        bool
+	ack        time.Duration
+	sinkOpts   []HTTPSinkOption
+}
+
+func WithSourcetype(sourcetype string) SplunkOption {
+	return func(c *splunkConfig) {
+		c.sourcetype = sourcetype
+	}
+}
+
+func WithIndex(index string) SplunkOption {
+	return func(c *splunkConfig) {
+		c.index = index
+	}
+}
+
+func WithSource(source string) SplunkOption {
+	return func(c *splunkConfig) {
+		c.source = source
+	}
+}
+
+func WithHost(host string) SplunkOption {
+	return func(c *splunkConfig) {
+		c.host = host
+	}
+}
+
+// WithRawMode sends entries to the raw endpoint as lines, leaving event
+// breaking to Splunk, instead of one event per entry.
+func WithRawMode() SplunkOption {
+	return func(c *splunkConfig) {
+		c.raw = true
+	}
+}
+
+// WithAck enables indexer acknowledgment, pending acknowledgments are
+// polled every interval, see SplunkSink.Pending.
+func WithAck(interval time.Duration) SplunkOption {
+	return func(c *splunkConfig) {
+		c.ack = interval
+	}
+}
+
+// WithHTTPSinkOptions configures the underlying HTTPSink, e.g. batching.
+func WithHTTPSinkOptions(opts ...HTTPSinkOption) SplunkOption {
+	return func(c *splunkConfig) {
+		c.sinkOpts = append(c.sinkOpts, opts...)
+	}
+}
+
+// SplunkSink sends entries to a Splunk HTTP Event Collector at baseURL,
+// e.g. "https://splunk:8088".
+type SplunkSink struct {
+	*HTTPSink
+	cfg     splunkConfig
+	base    string
+	token   string
+	channel string
+
+	mu      sync.Mutex
+	pending map[int64]time.Time
+	done    chan struct{}
+	wg      sync.WaitGroup
+	closing sync.Once
+}
+
+func NewSplunkSink(baseURL, token string, opts ...SplunkOption) *SplunkSink {
+	s := &SplunkSink{
+		base:    strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		channel: newUUID(),
+		pending: make(map[int64]time.Time),
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(&s.cfg)
+	}
+
+	endpoint := s.base + "/services/collector/event"
+	if s.cfg.raw {
+		endpoint = s.base + "/services/collector/raw?" + s.rawQuery()
+	}
+	sinkOpts := append([]HTTPSinkOption{
+		WithRequestHeader("Authorization", "Splunk "+token),
+		WithRequestHeader("Content-Type", "application/json"),
+		WithRequestHeader("X-Splunk-Request-Channel", s.channel),
+	}, s.cfg.sinkOpts...)
+	s.HTTPSink = NewHTTPSink(endpoint, sinkOpts...)
+	if !s.cfg.raw {
+		s.HTTPSink.wrap = s.event
+	}
+	if s.cfg.ack > 0 {
+		s.HTTPSink.onResponse = s.track
+		s.wg.Add(1)
+		go s.poll()
+	}
+	return s
+}
+
+func (s *SplunkSink) rawQuery() string {
+	q := url.Values{}
+	for k, v := range map[string]string{
+		"sourcetype": s.cfg.sourcetype,
+		"index":      s.cfg.index,
+		"source":     s.cfg.source,
+		"host":       s.cfg.host,
+	} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	return q.Encode()
+}
+
+// event wraps entry in the HEC event envelope, entries which are not JSON
+// are sent as strings.
+func (s *SplunkSink) event(entry []byte) []byte {
+	ev := map[string]any{"event": json.RawMessage(entry)}
+	if !json.Valid(entry) {
+		ev["event"] = string(entry)
+	}
+	for k, v := range map[string]string{
+		"sourcetype": s.cfg.sourcetype,
+		"index":      s.cfg.index,
+		"source":     s.cfg.source,
+		"host":       s.cfg.host,
+	} {
+		if v != "" {
+			ev[k] = v
+		}
+	}
+	b, _ := json.Marshal(ev)
+	return b
+}
+
+func (s *SplunkSink) track(body []byte) error {
+	var resp struct {
+		Code  int    `json:"code"`
+		Text  string `json:"text"`
+		AckID *int64 `json:"ackId"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("logie: splunk: %w", err)
+	}
+	if resp.Code != 0 {
+		return fmt.Errorf("logie: splunk: %s (code %d)", resp.Text, resp.Code)
+	}
+	if resp.AckID != nil {
+		s.mu.Lock()
+		s.pending[*resp.AckID] = time.Now()
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+func (s *SplunkSink) poll() {
+	defer s.wg.Done()
+	t := time.NewTicker(s.cfg.ack)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := s.PollAcks(); err != nil && s.onError != nil {
+				s.onError(err)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Pending returns the number of batches sent but not yet acknowledged by
+// the indexers.
+func (s *SplunkSink) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending)
+}
+
+// PollAcks asks the collector which pending batches were indexed.
+func (s *SplunkSink) PollAcks() error {
+	s.mu.Lock()
+	ids := make([]int64, 0, len(s.pending))
+	for id := range s.pending {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+	if len(ids) == 0 {
+		return nil
+	}
+
+	body, _ := json.Marshal(map[string][]int64{"acks": ids})
+	req, err := http.NewRequest(http.MethodPost, s.base+"/services/collector/ack", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Splunk "+s.token)
+	req.Header.Set("X-Splunk-Request-Channel", s.channel)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return &httpStatusError{status: resp.StatusCode, body: readLimited(resp.Body, 512)}
+	}
+	var acks struct {
+		Acks map[string]bool `json:"acks"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&acks); err != nil {
+		return fmt.Errorf("logie: splunk: %w", err)
+	}
+
+	s.mu.Lock()
+	for id, ok := range acks.Acks {
+		if n, err := strconv.ParseInt(id, 10, 64); ok && err == nil {
+			delete(s.pending, n)
+		}
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// Close flushes the pending entries and stops polling acknowledgments.
+func (s *SplunkSink) Close() error {
+	err := s.HTTPSink.Close()
+	s.closing.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+	})
+	return err
+}
+
+func newUUID() string {
+	id := newID() + newID()
+	return id[:8] + "-" + id[8:12] + "-4" + id[13:16] + "-a" + id[17:20] + "-" + id[20:32]
+}
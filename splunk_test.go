@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hecServer answers like a Splunk HTTP Event Collector with
+// acknowledgments, every ack it is asked about is indexed.
+type hecServer struct {
+	mu     sync.Mutex
+	paths  []string
+	bodies []string
+	header http.Header
+	ackID  int64
+}
+
+func (h *hecServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.paths = append(h.paths, r.URL.String())
+	h.header = r.Header
+	if r.URL.Path == "/services/collector/ack" {
+		var req struct{ Acks []int64 }
+		json.Unmarshal(body, &req)
+		acks := map[string]bool{}
+		for _, id := range req.Acks {
+			acks[fmt.Sprint(id)] = true
+		}
+		json.NewEncoder(w).Encode(map[string]any{"acks": acks})
+		return
+	}
+	h.bodies = append(h.bodies, string(body))
+	fmt.Fprintf(w, `{"text":"Success","code":0,"ackId":%d}`, h.ackID)
+	h.ackID++
+}
+
+func TestSplunkSink(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []SplunkOption
+		entry    string
+		wantPath string
+		wantBody string
+	}{
+		{
+			name:     "json event",
+			opts:     []SplunkOption{WithSourcetype("app"), WithIndex("main")},
+			entry:    `{"message":"hi"}`,
+			wantPath: "/services/collector/event",
+			wantBody: `{"event":{"message":"hi"},"index":"main","sourcetype":"app"}`,
+		},
+		{
+			name:     "text event",
+			entry:    `hello world`,
+			wantPath: "/services/collector/event",
+			wantBody: `{"event":"hello world"}`,
+		},
+		{
+			name:     "raw",
+			opts:     []SplunkOption{WithRawMode(), WithHost("web-1")},
+			entry:    `hello world`,
+			wantPath: "/services/collector/raw?host=web-1",
+			wantBody: `hello world`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hec := &hecServer{}
+			srv := httptest.NewServer(hec)
+			defer srv.Close()
+			s := NewSplunkSink(srv.URL, "token", tt.opts...)
+			s.Write([]byte(tt.entry + "\n"))
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if len(hec.paths) != 1 || hec.paths[0] != tt.wantPath {
+				t.Errorf("requests %q, want %s", hec.paths, tt.wantPath)
+			}
+			if got := strings.TrimSpace(hec.bodies[0]); got != tt.wantBody {
+				t.Errorf("body %s, want %s", got, tt.wantBody)
+			}
+			if got := hec.header.Get("Authorization"); got != "Splunk token" {
+				t.Errorf("Authorization = %q", got)
+			}
+		})
+	}
+}
+
+func TestSplunkSinkAcks(t *testing.T) {
+	hec := &hecServer{}
+	srv := httptest.NewServer(hec)
+	defer srv.Close()
+	s := NewSplunkSink(srv.URL, "token", WithAck(time.Hour))
+	defer s.Close()
+	s.Write([]byte(`{"message":"hi"}` + "\n"))
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Pending(); n != 1 {
+		t.Fatalf("Pending() = %d, want 1", n)
+	}
+	if err := s.PollAcks(); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Pending(); n != 0 {
+		t.Errorf("Pending() = %d after the ack, want 0", n)
+	}
+}
+
+func TestSplunkSinkConcurrentClose(t *testing.T) {
+	srv := httptest.NewServer(&hecServer{})
+	defer srv.Close()
+	s := NewSplunkSink(srv.URL, "token", WithAck(time.Hour))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Close()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,253 @@
+// Package sqllog wraps a database/sql driver to log queries, their
+// arguments, duration and errors.
+package sqllog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+)
+
+// Logger is satisfied by *logie.Logger.
+type Logger interface {
+	Infof(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+type Option func(*config)
+
+type config struct {
+	logger Logger
+	redact func(v any) any
+}
+
+// WithArgs logs query arguments passed through redact, arguments are
+// replaced by "?" by default.
+func WithArgs(redact func(v any) any) Option {
+	return func(c *config) {
+		c.redact = redact
+	}
+}
+
+// Register wraps d and registers it with database/sql under name.
+func Register(name string, d driver.Driver, l Logger, opts ...Option) {
+	sql.Register(name, Wrap(d, l, opts...))
+}
+
+func Wrap(d driver.Driver, l Logger, opts ...Option) driver.Driver {
+	c := &config{logger: l}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return &wrapDriver{Driver: d, cfg: c}
+}
+
+func (c *config) log(op, query string, args []driver.NamedValue, start time.Time, err error) {
+	if err == driver.ErrSkip {
+		return
+	}
+	shown := make([]any, len(args))
+	for i, arg := range args {
+		if c.redact != nil {
+			shown[i] = c.redact(arg.Value)
+		} else {
+			shown[i] = "?"
+		}
+	}
+	elapsed := time.Since(start)
+	if err != nil {
+		c.logger.Errorf("sql %s query=%q args=%v duration=%s error=%v", op, query, shown, elapsed, err)
+		return
+	}
+	c.logger.Infof("sql %s query=%q args=%v duration=%s", op, query, shown, elapsed)
+}
+
+type wrapDriver struct {
+	driver.Driver
+	cfg *config
+}
+
+func (d *wrapDriver) Open(name string) (driver.Conn, error) {
+	start := time.Now()
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		d.cfg.log("open", "", nil, start, err)
+		return nil, err
+	}
+	return &wrapConn{Conn: conn, cfg: d.cfg}, nil
+}
+
+type wrapConn struct {
+	driver.Conn
+	cfg *config
+}
+
+func (c *wrapConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *wrapConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	start := time.Now()
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		c.cfg.log("prepare", query, nil, start, err)
+		return nil, err
+	}
+	return &wrapStmt{Stmt: stmt, query: query, cfg: c.cfg}, nil
+}
+
+func (c *wrapConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	start := time.Now()
+	var (
+		tx  driver.Tx
+		err error
+	)
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = b.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.Conn.Begin()
+	}
+	c.cfg.log("begin", "", nil, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &wrapTx{Tx: tx, cfg: c.cfg}, nil
+}
+
+func (c *wrapConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := e.ExecContext(ctx, query, args)
+	c.cfg.log("exec", query, args, start, err)
+	return res, err
+}
+
+func (c *wrapConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args)
+	c.cfg.log("query", query, args, start, err)
+	return rows, err
+}
+
+func (c *wrapConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *wrapConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *wrapConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if ch, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return ch.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type wrapTx struct {
+	driver.Tx
+	cfg *config
+}
+
+func (t *wrapTx) Commit() error {
+	start := time.Now()
+	err := t.Tx.Commit()
+	t.cfg.log("commit", "", nil, start, err)
+	return err
+}
+
+func (t *wrapTx) Rollback() error {
+	start := time.Now()
+	err := t.Tx.Rollback()
+	t.cfg.log("rollback", "", nil, start, err)
+	return err
+}
+
+type wrapStmt struct {
+	driver.Stmt
+	query string
+	cfg   *config
+}
+
+func (s *wrapStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), named(args))
+}
+
+func (s *wrapStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), named(args))
+}
+
+func (s *wrapStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var (
+		res driver.Result
+		err error
+	)
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = e.ExecContext(ctx, args)
+	} else {
+		res, err = s.Stmt.Exec(values(args))
+	}
+	s.cfg.log("exec", s.query, args, start, err)
+	return res, err
+}
+
+func (s *wrapStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var (
+		rows driver.Rows
+		err  error
+	)
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(values(args))
+	}
+	s.cfg.log("query", s.query, args, start, err)
+	return rows, err
+}
+
+func (s *wrapStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if ch, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return ch.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	nv := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		nv[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return nv
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	v := make([]driver.Value, len(args))
+	for i, arg := range args {
+		v[i] = arg.Value
+	}
+	return v
+}
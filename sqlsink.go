@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var sqlIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type SQLSinkOption func(*SQLSink)
+
+// WithMaxRows prunes the oldest entries once the table holds more than n
+// of them, checked every 100 writes. It counts rows, see WithMaxBytes to
+// bound the size.
+func WithMaxRows(n int64) SQLSinkOption {
+	return func(s *SQLSink) {
+		s.maxRows = n
+	}
+}
+
+// WithMaxBytes prunes the oldest entries once their messages and fields
+// take up more than size bytes, checked every 100 writes. Indexes and
+// the other columns are not counted.
+func WithMaxBytes(size int64) SQLSinkOption {
+	return func(s *SQLSink) {
+		s.maxBytes = size
+	}
+}
+
+// SQLSink stores entries in a table of an embedded database such as
+// SQLite, opened by the caller with the driver of its choice, for in-app
+// log search with Query. JSON entries are split into time, level, logger,
+// message and the remaining fields as JSON, other entries are stored
+// whole as the message.
+type SQLSink struct {
+	mu       sync.Mutex
+	db       *sql.DB
+	table    string
+	maxRows  int64
+	maxBytes int64
+	// bytes is the size of the stored messages and fields
+	bytes  int64
+	writes int
+}
+
+// NewSQLSink creates table if needed, the statements use ? placeholders
+// and SQLite types.
+func NewSQLSink(db *sql.DB, table string, opts ...SQLSinkOption) (*SQLSink, error) {
+	if !sqlIdent.MatchString(table) {
+		return nil, fmt.Errorf("logie: invalid table name %q", table)
+	}
+	s := &SQLSink{db: db, table: table}
+	for _, opt := range opts {
+		opt(s)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE IF NOT EXISTS ` + table + ` (
+			id      INTEGER PRIMARY KEY AUTOINCREMENT,
+			time    TEXT NOT NULL,
+			level   INTEGER NOT NULL,
+			logger  TEXT NOT NULL DEFAULT '',
+			message TEXT NOT NULL,
+			fields  TEXT
+		)`,
+		`CREATE INDEX IF NOT EXISTS ` + table + `_time ON ` + table + ` (time)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, err
+		}
+	}
+	if s.maxBytes > 0 {
+		if err := s.countBytes(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *SQLSink) Write(p []byte) (int, error) {
+	return s.WriteLevel(InfoLevel, p)
+}
+
+func (s *SQLSink) WriteLevel(lvl Level, p []byte) (int, error) {
+	rec := LogRecord{Time: time.Now(), Level: lvl, Message: string(bytes.TrimSuffix(p, []byte("\n")))}
+	var m map[string]any
+	if decodeJSON(p, &m) == nil {
+		rec = recordFromMap(m, rec)
+	}
+	var fields []byte
+	if len(rec.Fields) > 0 {
+		fields, _ = json.Marshal(rec.Fields)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.db.Exec(`INSERT INTO `+s.table+` (time, level, logger, message, fields) VALUES (?, ?, ?, ?, ?)`,
+		rec.Time.UTC().Format(sqlTimeLayout), int(rec.Level), rec.Logger, rec.Message, string(fields))
+	if err != nil {
+		return 0, err
+	}
+	s.bytes += int64(len(rec.Message) + len(fields))
+	if s.writes++; s.writes%100 == 0 {
+		if err := s.prune(); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// sqlRowSize is the size of a row counted by WithMaxBytes.
+const sqlRowSize = `LENGTH(message) + LENGTH(COALESCE(fields, ''))`
+
+// sqlTimeLayout has a fixed width so the text order of the time column is
+// its chronological order, RFC3339Nano drops trailing zeros. It still
+// parses with time.RFC3339Nano.
+const sqlTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+func (s *SQLSink) prune() error {
+	if s.maxRows > 0 {
+		_, err := s.db.Exec(`DELETE FROM `+s.table+` WHERE id <= (SELECT MAX(id) FROM `+s.table+`) - ?`, s.maxRows)
+		if err != nil {
+			return err
+		}
+		if s.maxBytes > 0 {
+			if err := s.countBytes(); err != nil {
+				return err
+			}
+		}
+	}
+	if s.maxBytes > 0 && s.bytes > s.maxBytes {
+		return s.pruneBytes()
+	}
+	return nil
+}
+
+func (s *SQLSink) countBytes() error {
+	return s.db.QueryRow(`SELECT COALESCE(SUM(` + sqlRowSize + `), 0) FROM ` + s.table).Scan(&s.bytes)
+}
+
+// pruneBytes deletes the oldest rows until the table is a tenth below
+// the limit, so it does not run again on the next check.
+func (s *SQLSink) pruneBytes() error {
+	excess := s.bytes - s.maxBytes + s.maxBytes/10
+	rows, err := s.db.Query(`SELECT id, ` + sqlRowSize + ` FROM ` + s.table + ` ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	var last, freed int64
+	for freed < excess && rows.Next() {
+		var size int64
+		if err := rows.Scan(&last, &size); err != nil {
+			rows.Close()
+			return err
+		}
+		freed += size
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if freed == 0 {
+		return nil
+	}
+	if _, err := s.db.Exec(`DELETE FROM `+s.table+` WHERE id <= ?`, last); err != nil {
+		return err
+	}
+	s.bytes -= freed
+	return nil
+}
+
+func recordFromMap(m map[string]any, rec LogRecord) LogRecord {
+	if v, ok := m["level"].(string); ok {
+		_ = rec.Level.UnmarshalText([]byte(v))
+	}
+	if v, ok := m["time"].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			rec.Time = t
+		}
+	}
+	rec.Message, _ = m["message"].(string)
+	rec.Logger, _ = m["logger"].(string)
+	// only the keys stored in columns of their own, file, func and errors
+	// stay with the fields
+	for _, k := range []string{"time", "level", "message", "logger", "schema_version"} {
+		delete(m, k)
+	}
+	rec.Fields = m
+	return rec
+}
+
+// LogRecord is an entry read back by SQLSink.Query.
+type LogRecord struct {
+	ID      int64
+	Time    time.Time
+	Level   Level
+	Logger  string
+	Message string
+	Fields  Fields
+}
+
+// LogQuery filters the entries returned by SQLSink.Query, zero values
+// match everything. Text is searched in the message and fields.
+type LogQuery struct {
+	MinLevel Level
+	Logger   string
+	Text     string
+	Since    time.Time
+	Until    time.Time
+	// Limit defaults to 100, the newest entries come first.
+	Limit int
+}
+
+func (s *SQLSink) Query(ctx context.Context, q LogQuery) ([]LogRecord, error) {
+	where := []string{"level >= ?"}
+	args := []any{int(q.MinLevel)}
+	if q.Logger != "" {
+		where = append(where, "(logger = ? OR logger LIKE ? ESCAPE '\\')")
+		args = append(args, q.Logger, likeEscape(q.Logger)+".%")
+	}
+	if q.Text != "" {
+		where = append(where, "(message LIKE ? ESCAPE '\\' OR fields LIKE ? ESCAPE '\\')")
+		pattern := "%" + likeEscape(q.Text) + "%"
+		args = append(args, pattern, pattern)
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "time >= ?")
+		args = append(args, q.Since.UTC().Format(sqlTimeLayout))
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "time < ?")
+		args = append(args, q.Until.UTC().Format(sqlTimeLayout))
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, time, level, logger, message, fields FROM `+s.table+
+		` WHERE `+strings.Join(where, " AND ")+` ORDER BY id DESC LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []LogRecord
+	for rows.Next() {
+		var (
+			rec    LogRecord
+			ts     string
+			lvl    int
+			fields sql.NullString
+		)
+		if err := rows.Scan(&rec.ID, &ts, &lvl, &rec.Logger, &rec.Message, &fields); err != nil {
+			return nil, err
+		}
+		rec.Time, _ = time.Parse(time.RFC3339Nano, ts)
+		rec.Level = Level(lvl)
+		if fields.Valid && fields.String != "" {
+			_ = json.Unmarshal([]byte(fields.String), &rec.Fields)
+		}
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecordFromMap(t *testing.T) {
+	tests := []struct {
+		name   string
+		entry  string
+		want   LogRecord
+		fields Fields
+	}{
+		{
+			name:  "columns are split out",
+			entry: `{"schema_version":"3","level":"Warn","time":"2024-05-01T10:00:00Z","message":"slow","logger":"db","ms":12}`,
+			want:  LogRecord{Level: WarnLevel, Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), Message: "slow", Logger: "db"},
+			fields: Fields{
+				"ms": float64(12),
+			},
+		},
+		{
+			name:  "errors and the caller stay with the fields",
+			entry: `{"level":"Error","message":"failed","errors":["boom"],"file":"main.go:3","func":"main.run"}`,
+			want:  LogRecord{Level: ErrorLevel, Message: "failed"},
+			fields: Fields{
+				"errors": []any{"boom"},
+				"file":   "main.go:3",
+				"func":   "main.run",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m map[string]any
+			if err := decodeJSON([]byte(tt.entry), &m); err != nil {
+				t.Fatal(err)
+			}
+			got := recordFromMap(m, LogRecord{})
+			if !reflect.DeepEqual(got.Fields, tt.fields) {
+				t.Errorf("Fields = %v, want %v", got.Fields, tt.fields)
+			}
+			got.Fields = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("record = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLTimeLayoutSorts(t *testing.T) {
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	times := []time.Time{base, base.Add(100 * time.Millisecond), base.Add(120 * time.Millisecond), base.Add(time.Second)}
+	for i := 1; i < len(times); i++ {
+		a, b := times[i-1].Format(sqlTimeLayout), times[i].Format(sqlTimeLayout)
+		if a >= b {
+			t.Errorf("%s sorts after %s", a, b)
+		}
+		if parsed, err := time.Parse(time.RFC3339Nano, b); err != nil || !parsed.Equal(times[i]) {
+			t.Errorf("Parse(%s) = %v, %v", b, parsed, err)
+		}
+	}
+}
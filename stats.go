@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type statsKey struct {
+	sink string
+	lvl  Level
+}
+
+type statsCounter struct {
+	entries uint64
+	bytes   uint64
+	errors  uint64
+}
+
+type stats struct {
+	mu       sync.Mutex
+	counters map[statsKey]*statsCounter
+}
+
+// WithStats counts the entries, bytes and failed writes of every output
+// per level, see Stats. Outputs are named after their file or type,
+// route sinks after their route.
+func WithStats() Option {
+	return func(o *options) {
+		o.stats = &stats{counters: make(map[statsKey]*statsCounter)}
+	}
+}
+
+func (s *stats) record(sink string, lvl Level, n int, err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	c := s.counters[statsKey{sink, lvl}]
+	if c == nil {
+		c = &statsCounter{}
+		s.counters[statsKey{sink, lvl}] = c
+	}
+	if err != nil {
+		c.errors++
+	} else {
+		c.entries++
+		c.bytes += uint64(n)
+	}
+	s.mu.Unlock()
+}
+
+// SinkStats holds the counters of an output, by level name.
+type SinkStats struct {
+	Entries map[string]uint64
+	Bytes   map[string]uint64
+	Errors  map[string]uint64
+}
+
+type Stats struct {
+	Dropped uint64
+	Sinks   map[string]SinkStats
+}
+
+// Stats returns a snapshot of the counters enabled by WithStats. The
+// library does not import expvar, which registers /debug/vars on the
+// default mux, publish it with expvar.Func(func() any { return l.Stats() }).
+func (l *Logger) Stats() Stats {
+	st := Stats{Dropped: l.Dropped(), Sinks: map[string]SinkStats{}}
+	s := l.opt.stats
+	if s == nil {
+		return st
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, c := range s.counters {
+		ss, ok := st.Sinks[k.sink]
+		if !ok {
+			ss = SinkStats{Entries: map[string]uint64{}, Bytes: map[string]uint64{}, Errors: map[string]uint64{}}
+			st.Sinks[k.sink] = ss
+		}
+		lvl := LevelMapping[k.lvl]
+		ss.Entries[lvl] += c.entries
+		ss.Bytes[lvl] += c.bytes
+		ss.Errors[lvl] += c.errors
+	}
+	return st
+}
+
+// WritePrometheus writes Stats in the Prometheus text format.
+func (l *Logger) WritePrometheus(w io.Writer) error {
+	st := l.Stats()
+	sinks := make([]string, 0, len(st.Sinks))
+	for name := range st.Sinks {
+		sinks = append(sinks, name)
+	}
+	sort.Strings(sinks)
+
+	var b strings.Builder
+	for _, m := range []struct {
+		name, help string
+		get        func(SinkStats) map[string]uint64
+	}{
+		{"logie_entries_total", "Entries written per output and level.", func(s SinkStats) map[string]uint64 { return s.Entries }},
+		{"logie_bytes_total", "Bytes written per output and level.", func(s SinkStats) map[string]uint64 { return s.Bytes }},
+		{"logie_write_errors_total", "Failed writes per output and level.", func(s SinkStats) map[string]uint64 { return s.Errors }},
+	} {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", m.name, m.help, m.name)
+		for _, sink := range sinks {
+			values := m.get(st.Sinks[sink])
+			levels := make([]string, 0, len(values))
+			for lvl := range values {
+				levels = append(levels, lvl)
+			}
+			sort.Strings(levels)
+			for _, lvl := range levels {
+				fmt.Fprintf(&b, "%s{sink=\"%s\",level=\"%s\"} %d\n", m.name, promLabel(sink), promLabel(lvl), values[lvl])
+			}
+		}
+	}
+	fmt.Fprintf(&b, "# HELP logie_dropped_total Entries dropped by the async queue.\n# TYPE logie_dropped_total counter\nlogie_dropped_total %d\n", st.Dropped)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// promLabel escapes a label value, the text format only escapes
+// backslash, double quote and newline.
+var promLabel = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace
+
+// StatsHandler serves WritePrometheus, for a /metrics endpoint.
+func (l *Logger) StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_ = l.WritePrometheus(w)
+	})
+}
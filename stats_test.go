@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPromLabel(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"app.log", "app.log"},
+		{`C:\logs`, `C:\\logs`},
+		{`say "hi"`, `say \"hi\"`},
+		{"two\nlines", `two\nlines`},
+		{"tab\there", "tab\there"},
+		{"café", "café"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := promLabel(tt.in); got != tt.want {
+				t.Errorf("promLabel(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	var out, routed bytes.Buffer
+	l := New(WithPosition(&out), WithStats(), WithFormatter(&TextFormatter{IgnoreBasicFields: true}),
+		WithRoutes(&Route{Name: "audit\tlog", MinLevel: ErrorLevel, Sink: &routed}))
+	l.Info("hello")
+	l.Error("broken")
+
+	st := l.Stats()
+	sink := st.Sinks[describeOutput(&out)]
+	if sink.Entries["Info"] != 1 || sink.Entries["Error"] != 1 {
+		t.Errorf("entries = %v, want one per level", sink.Entries)
+	}
+	if want := uint64(len("hello\n")); sink.Bytes["Info"] != want {
+		t.Errorf("bytes = %d, want %d", sink.Bytes["Info"], want)
+	}
+	if route := st.Sinks["route:audit\tlog"]; route.Entries["Error"] != 1 {
+		t.Errorf("route entries = %v, want the error", route.Entries)
+	}
+
+	var b strings.Builder
+	if err := l.WritePrometheus(&b); err != nil {
+		t.Fatal(err)
+	}
+	if want := "logie_entries_total{sink=\"route:audit\tlog\",level=\"Error\"} 1\n"; !strings.Contains(b.String(), want) {
+		t.Errorf("metrics\n%s\nwant line %q", b.String(), want)
+	}
+}
+
+func TestStatsErrors(t *testing.T) {
+	l := New(WithPosition(failingWriter{}), WithStats())
+	l.Warn("lost")
+	st := l.Stats()
+	if n := st.Sinks[describeOutput(failingWriter{})].Errors["Warn"]; n != 1 {
+		t.Errorf("errors = %d, want 1", n)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, io.ErrClosedPipe }
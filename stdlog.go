@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"strings"
+)
+
+// RedirectStdLog points the output of the standard log package at l:
+// each line becomes an entry at level, or at the level named by a
+// leading tag such as "[WARN]" or "error:", which is stripped. A prefix
+// set with log.SetPrefix is kept as the log_prefix field. restore puts
+// back the previous output, flags and prefix.
+func RedirectStdLog(l *Logger, level Level) (restore func()) {
+	out, flags, prefix := log.Writer(), log.Flags(), log.Prefix()
+
+	logger := l.derive(func(o *options) {
+		// the caller would be the log package
+		o.disableCaller = true
+	})
+	if p := strings.TrimSpace(prefix); p != "" {
+		logger = logger.WithFields(Fields{"log_prefix": p})
+	}
+	log.SetOutput(&stdLogWriter{logger: logger, level: level})
+	log.SetFlags(0)
+	log.SetPrefix("")
+
+	return func() {
+		log.SetOutput(out)
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+	}
+}
+
+type stdLogWriter struct {
+	logger *Logger
+	level  Level
+}
+
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	msg := string(bytes.TrimSuffix(p, []byte("\n")))
+	lvl := w.level
+	if tagged, rest, ok := stdLogLevel(msg); ok {
+		lvl, msg = tagged, rest
+	}
+	if w.logger.enabled(lvl) {
+		w.logger.entry().write(lvl, FmtEmptySeparate, msg)
+	}
+	return len(p), nil
+}
+
+// stdLogLevel recognizes a level tag at the start of msg.
+func stdLogLevel(msg string) (Level, string, bool) {
+	tag := msg
+	if i := strings.IndexAny(msg, " \t"); i > 0 {
+		tag = msg[:i]
+	}
+	name := strings.Trim(tag, "[]:")
+	if name == "" || len(name) == len(tag) && !strings.HasSuffix(tag, ":") {
+		return 0, msg, false
+	}
+	switch strings.ToLower(name) {
+	case "trace":
+		return TraceLevel, strings.TrimSpace(msg[len(tag):]), true
+	case "debug":
+		return DebugLevel, strings.TrimSpace(msg[len(tag):]), true
+	case "info":
+		return InfoLevel, strings.TrimSpace(msg[len(tag):]), true
+	case "warn", "warning":
+		return WarnLevel, strings.TrimSpace(msg[len(tag):]), true
+	case "error", "err":
+		return ErrorLevel, strings.TrimSpace(msg[len(tag):]), true
+	}
+	return 0, msg, false
+}
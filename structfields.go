@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+type structField struct {
+	index     int
+	name      string
+	omitEmpty bool
+	secret    bool
+}
+
+// structPlans caches the exported, tagged fields of struct types.
+var structPlans sync.Map // reflect.Type -> []structField
+
+// WithStructExpansion expands struct values into fields, following
+// `logie:"name,omitempty"` tags, "-" to skip a field and the secret
+// option to mask its value, see Secret, down to depth
+// nested structs. A struct field value becomes dotted keys, such as
+// order.id, and the struct arguments of the non-f methods are moved out
+// of the message into top level fields. Keys are expanded before
+// redaction, pseudonymization and IP anonymization apply.
+func WithStructExpansion(depth int) Option {
+	return func(o *options) {
+		o.structDepth = depth
+	}
+}
+
+// StructFields returns the fields of the struct v, or of the struct it
+// points to, see WithStructExpansion. It returns nil for other values.
+func StructFields(v any, depth int) Fields {
+	if !expandable(v) {
+		return nil
+	}
+	fields := Fields{}
+	appendStruct(fields, "", reflect.Indirect(reflect.ValueOf(v)), depth)
+	return fields
+}
+
+func (e *Entry) expandStructs(fields Fields, depth int) Fields {
+	var expanded Fields
+	for k, v := range fields {
+		if expandable(v) {
+			if expanded == nil {
+				expanded = mergeFields(nil, fields)
+			}
+			delete(expanded, k)
+			appendStruct(expanded, k+".", reflect.Indirect(reflect.ValueOf(v)), depth)
+		}
+	}
+	if expanded == nil {
+		expanded = fields
+	}
+	if e.Format != FmtEmptySeparate {
+		return expanded
+	}
+
+	var args []any
+	for i, arg := range e.Args {
+		if !expandable(arg) {
+			if args != nil {
+				args = append(args, arg)
+			}
+			continue
+		}
+		if args == nil {
+			args = append(make([]any, 0, len(e.Args)), e.Args[:i]...)
+			expanded = mergeFields(nil, expanded)
+		}
+		appendStruct(expanded, "", reflect.Indirect(reflect.ValueOf(arg)), depth)
+	}
+	if args != nil {
+		e.Args = args
+	}
+	return expanded
+}
+
+// expandable reports whether v is a struct, or a pointer to one, with no
+// method of its own deciding how it is logged.
+func expandable(v any) bool {
+	switch v.(type) {
+	case nil, time.Time, *time.Time, error, fmt.Stringer, Valuer,
+		json.Marshaler, encoding.TextMarshaler:
+		return false
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return false
+		}
+		rv = rv.Elem()
+	}
+	return rv.Kind() == reflect.Struct
+}
+
+func appendStruct(fields Fields, prefix string, rv reflect.Value, depth int) {
+	for _, f := range structPlan(rv.Type()) {
+		fv := rv.Field(f.index)
+		if f.omitEmpty && fv.IsZero() {
+			continue
+		}
+		key := prefix + f.name
+		if f.secret {
+			fields[key] = redacted
+			continue
+		}
+		v := fv.Interface()
+		if expandable(v) {
+			if depth > 0 {
+				if fv.Kind() == reflect.Interface {
+					fv = fv.Elem()
+				}
+				appendStruct(fields, key+".", reflect.Indirect(fv), depth-1)
+				continue
+			}
+			v = fmt.Sprintf("%+v", v)
+		}
+		fields[key] = v
+	}
+}
+
+func structPlan(t reflect.Type) []structField {
+	if plan, ok := structPlans.Load(t); ok {
+		return plan.([]structField)
+	}
+	var plan []structField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		f := structField{index: i, name: sf.Name}
+		if tag, ok := sf.Tag.Lookup("logie"); ok {
+			if tag == "-" {
+				continue
+			}
+			parts := strings.Split(tag, ",")
+			if parts[0] != "" {
+				f.name = parts[0]
+			}
+			for _, opt := range parts[1:] {
+				switch opt {
+				case "omitempty":
+					f.omitEmpty = true
+				case "secret":
+					f.secret = true
+				}
+			}
+		}
+		plan = append(plan, f)
+	}
+	structPlans.Store(t, plan)
+	return plan
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+type structAddress struct {
+	City string `logie:"city"`
+}
+
+type structOrder struct {
+	ID      int `logie:"id"`
+	Payload any `logie:"payload"`
+	Meta    interface{ Len() int }
+}
+
+type structMeta struct {
+	Size int `logie:"size"`
+}
+
+func (structMeta) Len() int { return 0 }
+
+func TestStructFieldsInterface(t *testing.T) {
+	tests := []struct {
+		name  string
+		order structOrder
+		want  Fields
+	}{
+		{
+			name:  "struct",
+			order: structOrder{ID: 1, Payload: structAddress{City: "Oslo"}},
+			want:  Fields{"id": 1, "payload.city": "Oslo", "Meta": nil},
+		},
+		{
+			name:  "pointer",
+			order: structOrder{ID: 2, Payload: &structAddress{City: "Lima"}},
+			want:  Fields{"id": 2, "payload.city": "Lima", "Meta": nil},
+		},
+		{
+			name:  "method set",
+			order: structOrder{ID: 3, Meta: structMeta{Size: 4}},
+			want:  Fields{"id": 3, "payload": nil, "Meta.size": 4},
+		},
+		{
+			name:  "scalar",
+			order: structOrder{ID: 4, Payload: "raw"},
+			want:  Fields{"id": 4, "payload": "raw", "Meta": nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := StructFields(tt.order, 2)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("StructFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStructFieldsInterfaceDepth(t *testing.T) {
+	got := StructFields(structOrder{ID: 1, Payload: structAddress{City: "Oslo"}}, 0)
+	if got["payload"] != "{City:Oslo}" {
+		t.Errorf("payload = %v, want the formatted struct", got["payload"])
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import "fmt"
+
+// badKey holds the dangling value of an odd key-value list.
+const badKey = "!BADKEY"
+
+// wEntry turns alternating keys and values into the fields of an entry,
+// a non-string key is formatted with fmt.Sprint.
+func (l *Logger) wEntry(keysAndValues []any) *Entry {
+	e := l.entry()
+	if len(keysAndValues) == 0 {
+		return e
+	}
+	e.extra = make(Fields, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 == len(keysAndValues) {
+			e.extra[badKey] = keysAndValues[i]
+			if l.opt.formatCheck {
+				l.misuse(2, "logie: odd number of key-value arguments", Fields{
+					"problem": fmt.Sprintf("%d arguments, value %v has no key", len(keysAndValues), keysAndValues[i]),
+				})
+			}
+			break
+		}
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		e.extra[key] = keysAndValues[i+1]
+	}
+	return e
+}
+
+func (l *Logger) Debugw(msg string, keysAndValues ...any) {
+	if l.enabled(DebugLevel) {
+		l.wEntry(keysAndValues).write(DebugLevel, FmtEmptySeparate, msg)
+	}
+}
+
+func (l *Logger) Infow(msg string, keysAndValues ...any) {
+	if l.enabled(InfoLevel) {
+		l.wEntry(keysAndValues).write(InfoLevel, FmtEmptySeparate, msg)
+	}
+}
+
+func (l *Logger) Warnw(msg string, keysAndValues ...any) {
+	if l.enabled(WarnLevel) {
+		l.wEntry(keysAndValues).write(WarnLevel, FmtEmptySeparate, msg)
+	}
+}
+
+func (l *Logger) Errorw(msg string, keysAndValues ...any) {
+	if l.enabled(ErrorLevel) {
+		l.wEntry(keysAndValues).write(ErrorLevel, FmtEmptySeparate, msg)
+	}
+}
+
+func Debugw(msg string, keysAndValues ...any) {
+	if std.enabled(DebugLevel) {
+		std.wEntry(keysAndValues).write(DebugLevel, FmtEmptySeparate, msg)
+	}
+}
+
+func Infow(msg string, keysAndValues ...any) {
+	if std.enabled(InfoLevel) {
+		std.wEntry(keysAndValues).write(InfoLevel, FmtEmptySeparate, msg)
+	}
+}
+
+func Warnw(msg string, keysAndValues ...any) {
+	if std.enabled(WarnLevel) {
+		std.wEntry(keysAndValues).write(WarnLevel, FmtEmptySeparate, msg)
+	}
+}
+
+func Errorw(msg string, keysAndValues ...any) {
+	if std.enabled(ErrorLevel) {
+		std.wEntry(keysAndValues).write(ErrorLevel, FmtEmptySeparate, msg)
+	}
+}
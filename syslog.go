@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultSDID is the SD-ID of SyslogFormatter, 32473 is the private
+// enterprise number reserved for documentation.
+const DefaultSDID = "logie@32473"
+
+// syslogTimeLayout keeps at most the 6 fractional digits of TIME-SECFRAC.
+const syslogTimeLayout = "2006-01-02T15:04:05.999999Z07:00"
+
+// SyslogFormatter formats entries as RFC 5424 syslog messages. Fields go
+// into a STRUCTURED-DATA element instead of the MSG, so syslog parsers
+// keep them as key-value pairs.
+type SyslogFormatter struct {
+	// Facility of the PRI, 1 (user) when zero. Values outside 0-23 are
+	// reported and replaced by 1.
+	Facility int
+	// SDID names the structured data element, DefaultSDID when empty.
+	SDID string
+	// Hostname and AppName default to the host name and program name.
+	Hostname string
+	AppName  string
+	MsgID    string
+}
+
+func (f *SyslogFormatter) Format(e *Entry) error {
+	var err error
+	facility := f.Facility
+	if facility < 0 || facility > 23 {
+		err = fmt.Errorf("logie: syslog facility %d out of range 0-23", facility)
+		facility = 1
+	} else if facility == 0 {
+		facility = 1
+	}
+	hostname, appName := f.Hostname, f.AppName
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+	if appName == "" {
+		appName = filepath.Base(os.Args[0])
+	}
+	sdID := f.SDID
+	if sdID == "" {
+		sdID = DefaultSDID
+	}
+
+	fmt.Fprintf(e.Buf, "<%d>1 %s %s %s %d %s ",
+		facility*8+syslogSeverity[e.Level],
+		e.Time.Format(syslogTimeLayout),
+		syslogHeader(hostname, 255),
+		syslogHeader(appName, 48),
+		os.Getpid(),
+		syslogHeader(f.MsgID, 32),
+	)
+
+	keys := e.Fields.keys()
+	errs := argErrors(e.Args)
+	if len(keys) == 0 && e.File == "" && len(errs) == 0 {
+		e.Buf.WriteString("-")
+	} else {
+		e.Buf.WriteString("[" + sdID)
+		if e.File != "" {
+			fmt.Fprintf(e.Buf, ` caller="%s"`, sdEscape(callSiteString(e.File, e.Line)))
+		}
+		// a PARAM-NAME may repeat, one per error like the JSON array
+		for _, msg := range errs {
+			fmt.Fprintf(e.Buf, ` errors="%s"`, sdEscape(msg))
+		}
+		for _, k := range keys {
+			fmt.Fprintf(e.Buf, ` %s="%s"`, sdName(k), sdEscape(fmt.Sprint(e.Fields[k])))
+		}
+		e.Buf.WriteString("]")
+	}
+
+	e.Buf.WriteString(" ")
+	switch e.Format {
+	case FmtEmptySeparate:
+		e.Buf.WriteString(fmt.Sprint(e.Args...))
+	default:
+		e.Buf.WriteString(fmt.Sprintf(e.Format, e.Args...))
+	}
+	e.Buf.WriteString("\n")
+	return err
+}
+
+// syslogHeader returns s as a header field: NILVALUE when empty, printable
+// ASCII only, at most max bytes.
+func syslogHeader(s string, max int) string {
+	if s == "" {
+		return "-"
+	}
+	b := []byte(s)
+	for i, c := range b {
+		if c < 33 || c > 126 {
+			b[i] = '_'
+		}
+	}
+	if len(b) > max {
+		b = b[:max]
+	}
+	return string(b)
+}
+
+// sdName makes key a valid PARAM-NAME: 32 printable ASCII characters
+// other than '=', ' ', ']' and '"'.
+func sdName(key string) string {
+	b := []byte(key)
+	for i, c := range b {
+		if c < 33 || c > 126 || c == '=' || c == ']' || c == '"' {
+			b[i] = '_'
+		}
+	}
+	if len(b) > 32 {
+		b = b[:32]
+	}
+	if len(b) == 0 {
+		return "_"
+	}
+	return string(b)
+}
+
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+func sdEscape(v string) string {
+	return sdEscaper.Replace(v)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+type joinedErrors []error
+
+func (j joinedErrors) Error() string   { return "joined" }
+func (j joinedErrors) Unwrap() []error { return j }
+
+// syslogTimestamp matches an RFC 5424 TIMESTAMP, TIME-SECFRAC has 1 to 6
+// digits.
+var syslogTimestamp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d{1,6})?(Z|[+-]\d{2}:\d{2})$`)
+
+func formatSyslog(t *testing.T, f *SyslogFormatter, e *Entry) (string, error) {
+	t.Helper()
+	e.Buf.Reset()
+	err := f.Format(e)
+	return e.Buf.String(), err
+}
+
+func TestSyslogFormatterTimestamp(t *testing.T) {
+	tests := []struct {
+		name string
+		time time.Time
+		want string
+	}{
+		{"nanoseconds", time.Date(2024, 5, 1, 10, 2, 17, 638049667, time.UTC), "2024-05-01T10:02:17.638049Z"},
+		{"whole seconds", time.Date(2024, 5, 1, 10, 2, 17, 0, time.UTC), "2024-05-01T10:02:17Z"},
+		{"offset", time.Date(2024, 5, 1, 10, 2, 17, 500000000, time.FixedZone("", 2*3600)), "2024-05-01T10:02:17.5+02:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEntry(InfoLevel, "hello", nil)
+			e.Time = tt.time
+			out, err := formatSyslog(t, &SyslogFormatter{Hostname: "host", AppName: "app"}, e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ts := strings.Fields(out)[1]
+			if ts != tt.want || !syslogTimestamp.MatchString(ts) {
+				t.Errorf("timestamp = %s, want %s", ts, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyslogFormatterFacility(t *testing.T) {
+	tests := []struct {
+		facility int
+		pri      string
+		wantErr  bool
+	}{
+		{0, "<14>", false},
+		{16, "<134>", false},
+		{23, "<190>", false},
+		{24, "<14>", true},
+		{-1, "<14>", true},
+	}
+	for _, tt := range tests {
+		e := NewEntry(InfoLevel, "hello", nil)
+		out, err := formatSyslog(t, &SyslogFormatter{Facility: tt.facility, Hostname: "h", AppName: "a"}, e)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("facility %d: error = %v, want error %v", tt.facility, err, tt.wantErr)
+		}
+		if !strings.HasPrefix(out, tt.pri) {
+			t.Errorf("facility %d: message %q, want PRI %s", tt.facility, out, tt.pri)
+		}
+	}
+}
+
+func TestSyslogFormatterStructuredData(t *testing.T) {
+	e := NewEntry(ErrorLevel, "", Fields{"path": `a"b]c\d`})
+	e.Args = []any{"failed ", joinedErrors{errors.New("disk full"), errors.New("retry]")}}
+	out, err := formatSyslog(t, &SyslogFormatter{Hostname: "h", AppName: "a"}, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[logie@32473 errors="disk full" errors="retry\]" path="a\"b\]c\\d"] failed joined`
+	if !strings.Contains(out, want) {
+		t.Errorf("message %q does not contain %q", out, want)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+)
+
+// TaskGroup is satisfied by errgroup.Group and similar worker pools.
+type TaskGroup interface {
+	Go(fn func() error)
+}
+
+// Tasks instruments the tasks of a group, every entry carries the same
+// group_id so the lifetime of a batch can be followed across goroutines.
+type Tasks struct {
+	logger *Logger
+	group  TaskGroup
+	seq    uint64
+}
+
+func (l *Logger) Tasks(g TaskGroup) *Tasks {
+	return &Tasks{logger: l.WithFields(Fields{"group_id": newID()}), group: g}
+}
+
+// Go runs fn on the underlying group, see Wrap.
+func (t *Tasks) Go(name string, fn func() error) {
+	t.group.Go(t.Wrap(name, fn))
+}
+
+// Wrap returns fn instrumented to log its start, completion, latency and
+// error. A panic in fn is logged with its stack and returned as an error.
+func (t *Tasks) Wrap(name string, fn func() error) func() error {
+	seq := atomic.AddUint64(&t.seq, 1)
+	return func() (err error) {
+		l := t.logger.WithFields(Fields{"task": name, "task_seq": seq})
+		start := l.now()
+		l.Debug("task started")
+
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("task %s panicked: %v", name, r)
+				fields := PanicValue(r)
+				for k, v := range Latency(start, l.now()) {
+					fields[k] = v
+				}
+				l.WithFields(fields).Error(err)
+				return
+			}
+			l = l.WithFields(Latency(start, l.now()))
+			if err != nil {
+				l.WithFields(Fields{"error": err.Error()}).Error("task failed")
+				return
+			}
+			l.Info("task finished")
+		}()
+		return fn()
+	}
+}
+
+func newID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
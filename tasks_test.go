@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// syncGroup runs the tasks on the calling goroutine.
+type syncGroup struct{ errs []error }
+
+func (g *syncGroup) Go(fn func() error) { g.errs = append(g.errs, fn()) }
+
+// stepClock advances by step every time it is read.
+func stepClock(step time.Duration) func() time.Time {
+	now := time.Unix(0, 0)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func TestTasks(t *testing.T) {
+	tests := []struct {
+		name    string
+		fn      func() error
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "finished",
+			fn:   func() error { return nil },
+			want: []string{"task finished", "latency=1s", "latency_ms=1000", "task=job", "task_seq=1"},
+		},
+		{
+			name:    "failed",
+			fn:      func() error { return errors.New("boom") },
+			want:    []string{"task failed", "error=boom", "latency=1s"},
+			wantErr: "boom",
+		},
+		{
+			name:    "panicked",
+			fn:      func() error { panic("oops") },
+			want:    []string{"task job panicked: oops", "latency=1s", "panic=true"},
+			wantErr: "task job panicked: oops",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &collector{}
+			l := New(WithPosition(out), WithFormatter(&TextFormatter{IgnoreBasicFields: true}), WithLevel(InfoLevel),
+				WithClock(stepClock(time.Second)))
+			g := &syncGroup{}
+			l.Tasks(g).Go("job", tt.fn)
+
+			if err := g.errs[0]; tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("task returned %v, want %q", err, tt.wantErr)
+			}
+			got := out.got()
+			if len(got) != 1 {
+				t.Fatalf("logged %q, want one completion entry", got)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got[0], want) {
+					t.Errorf("entry %q lacks %q", got[0], want)
+				}
+			}
+			if !strings.Contains(got[0], "group_id=") {
+				t.Errorf("entry %q lacks the group id", got[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type FactoryOption func(*LoggerFactory)
+
+// LoggerFactory hands out one logger per tenant, each writing to its own
+// output. Once more than the configured maximum are open, the least
+// recently used tenants are closed, provided they are not held through
+// Acquire and neither requested nor written to for the idle time of
+// WithTenantIdle. A logger must not be used after its tenant was evicted.
+type LoggerFactory struct {
+	mu      sync.Mutex
+	open    func(tenant string) (io.Writer, error)
+	opts    []Option
+	max     int
+	idle    time.Duration
+	limit   int64
+	window  time.Duration
+	lru     *list.List
+	tenants map[string]*list.Element
+}
+
+type tenantLogger struct {
+	id     string
+	logger *Logger
+	out    io.Writer
+	refs   int
+	// used is the Unix nanosecond time of the last Get or write
+	used int64
+}
+
+func (t *tenantLogger) touch() {
+	atomic.StoreInt64(&t.used, time.Now().UnixNano())
+}
+
+func (t *tenantLogger) idleFor() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&t.used)))
+}
+
+// activityWriter records the writes of a tenant logger.
+type activityWriter struct {
+	io.Writer
+	t *tenantLogger
+}
+
+func (w *activityWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(InfoLevel, p)
+}
+
+// WriteLevel keeps the level for a LevelWriter underneath, such as a
+// FileWriter with a disk guard.
+func (w *activityWriter) WriteLevel(lvl Level, p []byte) (int, error) {
+	w.t.touch()
+	if err := writeFull(w.Writer, lvl, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *activityWriter) Healthy() error {
+	return probe(w.Writer)
+}
+
+func WithMaxTenants(n int) FactoryOption {
+	return func(f *LoggerFactory) {
+		f.max = n
+	}
+}
+
+// WithTenantIdle sets how long a tenant must go unused before it can be
+// evicted to respect WithMaxTenants, one minute by default.
+func WithTenantIdle(d time.Duration) FactoryOption {
+	return func(f *LoggerFactory) {
+		f.idle = d
+	}
+}
+
+// WithTenantQuota drops entries of a tenant once it wrote more than limit
+// bytes in the current window.
+func WithTenantQuota(limit int64, window time.Duration) FactoryOption {
+	return func(f *LoggerFactory) {
+		f.limit, f.window = limit, window
+	}
+}
+
+// WithTenantOptions applies opts to every tenant logger.
+func WithTenantOptions(opts ...Option) FactoryOption {
+	return func(f *LoggerFactory) {
+		f.opts = append(f.opts, opts...)
+	}
+}
+
+// TenantFiles opens dir/<tenant>.log for each tenant.
+func TenantFiles(dir string, opts ...FileOption) func(tenant string) (io.Writer, error) {
+	return func(tenant string) (io.Writer, error) {
+		if tenant == "" || tenant != filepath.Base(tenant) || strings.HasPrefix(tenant, ".") {
+			return nil, fmt.Errorf("logie: invalid tenant id %q", tenant)
+		}
+		return NewFileWriter(filepath.Join(dir, tenant+".log"), opts...)
+	}
+}
+
+func NewLoggerFactory(open func(tenant string) (io.Writer, error), opts ...FactoryOption) *LoggerFactory {
+	f := &LoggerFactory{
+		open:    open,
+		max:     100,
+		idle:    time.Minute,
+		lru:     list.New(),
+		tenants: make(map[string]*list.Element),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+func (f *LoggerFactory) Get(tenant string) (*Logger, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t, err := f.get(tenant)
+	if err != nil {
+		return nil, err
+	}
+	return t.logger, nil
+}
+
+// Acquire returns the logger of tenant like Get and keeps the tenant open
+// until release is called, for loggers held longer than the idle time.
+func (f *LoggerFactory) Acquire(tenant string) (l *Logger, release func(), err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t, err := f.get(tenant)
+	if err != nil {
+		return nil, nil, err
+	}
+	t.refs++
+	var once sync.Once
+	return t.logger, func() {
+		once.Do(func() {
+			f.mu.Lock()
+			t.refs--
+			t.touch()
+			f.mu.Unlock()
+		})
+	}, nil
+}
+
+func (f *LoggerFactory) get(tenant string) (*tenantLogger, error) {
+	if el, ok := f.tenants[tenant]; ok {
+		t := el.Value.(*tenantLogger)
+		t.touch()
+		f.lru.MoveToFront(el)
+		return t, nil
+	}
+
+	out, err := f.open(tenant)
+	if err != nil {
+		return nil, err
+	}
+	t := &tenantLogger{id: tenant, out: out}
+	t.touch()
+	var pos io.Writer = &activityWriter{Writer: out, t: t}
+	if f.limit > 0 {
+		pos = &quotaWriter{w: pos, q: newQuota(f.limit, f.window)}
+	}
+	opts := append(append([]Option{}, f.opts...), WithPosition(pos))
+	t.logger = New(opts...).WithFields(Fields{"tenant": tenant})
+	f.tenants[tenant] = f.lru.PushFront(t)
+
+	// busy tenants are kept even above the maximum
+	for el := f.lru.Back(); el != nil && f.max > 0 && f.lru.Len() > f.max; {
+		prev := el.Prev()
+		if bt := el.Value.(*tenantLogger); bt != t && bt.refs == 0 && bt.idleFor() >= f.idle {
+			_ = f.remove(el)
+		}
+		el = prev
+	}
+	return t, nil
+}
+
+func (f *LoggerFactory) Evict(tenant string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if el, ok := f.tenants[tenant]; ok {
+		return f.remove(el)
+	}
+	return nil
+}
+
+// EvictIdle closes tenants neither requested nor written to for longer
+// than idle, except those held through Acquire.
+func (f *LoggerFactory) EvictIdle(idle time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for el := f.lru.Back(); el != nil; {
+		prev := el.Prev()
+		if t := el.Value.(*tenantLogger); t.refs == 0 && t.idleFor() > idle {
+			_ = f.remove(el)
+		}
+		el = prev
+	}
+}
+
+func (f *LoggerFactory) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var first error
+	for f.lru.Len() > 0 {
+		if err := f.remove(f.lru.Back()); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// remove stops the goroutines of the tenant logger, draining its async
+// queue, then closes its output.
+func (f *LoggerFactory) remove(el *list.Element) error {
+	t := f.lru.Remove(el).(*tenantLogger)
+	delete(f.tenants, t.id)
+	err := t.logger.Close()
+	if c, ok := t.out.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+type quotaWriter struct {
+	w io.Writer
+	q *quota
+}
+
+func (w *quotaWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(InfoLevel, p)
+}
+
+func (w *quotaWriter) WriteLevel(lvl Level, p []byte) (int, error) {
+	if ok, _ := w.q.take(time.Now(), int64(len(p))); !ok {
+		return len(p), nil
+	}
+	if err := writeFull(w.w, lvl, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *quotaWriter) Healthy() error {
+	return probe(w.w)
+}
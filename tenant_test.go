@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// levelRecorder is a LevelWriter remembering the level of each write.
+type levelRecorder struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	levels []Level
+}
+
+func (r *levelRecorder) Write(p []byte) (int, error) {
+	return r.WriteLevel(InfoLevel, p)
+}
+
+func (r *levelRecorder) WriteLevel(lvl Level, p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.levels = append(r.levels, lvl)
+	return r.buf.Write(p)
+}
+
+func TestTenantWritersKeepLevels(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []FactoryOption
+	}{
+		{"activity", nil},
+		{"quota", []FactoryOption{WithTenantQuota(1<<20, time.Minute)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &levelRecorder{}
+			open := func(string) (io.Writer, error) { return rec, nil }
+			f := NewLoggerFactory(open, tt.opts...)
+			l, err := f.Get("acme")
+			if err != nil {
+				t.Fatal(err)
+			}
+			l.Warn("careful")
+			l.Error("broken")
+			if len(rec.levels) != 2 || rec.levels[0] != WarnLevel || rec.levels[1] != ErrorLevel {
+				t.Errorf("levels = %v, want [Warn Error]", rec.levels)
+			}
+		})
+	}
+}
+
+func TestTenantQuota(t *testing.T) {
+	rec := &levelRecorder{}
+	open := func(string) (io.Writer, error) { return rec, nil }
+	f := NewLoggerFactory(open, WithTenantQuota(64, time.Hour),
+		WithTenantOptions(WithFormatter(&TextFormatter{IgnoreBasicFields: true})))
+	l, err := f.Get("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		l.Info("0123456789")
+	}
+	if n := rec.buf.Len(); n == 0 || n > 64 {
+		t.Errorf("wrote %d bytes, want at most the quota of 64", n)
+	}
+}
+
+func TestTenantFilesDiskGuard(t *testing.T) {
+	dir := t.TempDir()
+	// no file system has this much free space, the guard pauses at once
+	f := NewLoggerFactory(TenantFiles(dir, WithDiskGuard(1<<62, DiskPause, ErrorLevel)),
+		WithTenantOptions(WithFormatter(&TextFormatter{IgnoreBasicFields: true})))
+	l, err := f.Get("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("dropped")
+	l.Error("kept")
+	f.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, "acme.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "kept tenant=acme" {
+		t.Errorf("file = %q, want only the entry at the guarded level", got)
+	}
+}
+
+func TestTenantFilesRejects(t *testing.T) {
+	open := TenantFiles(t.TempDir())
+	for _, id := range []string{"", "..", ".hidden", "a/b"} {
+		t.Run(id, func(t *testing.T) {
+			if _, err := open(id); err == nil {
+				t.Errorf("open(%q) succeeded", id)
+			}
+		})
+	}
+}
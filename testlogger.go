@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+// TB is the part of testing.TB the test helpers use. It is declared here
+// so programs using the logger do not link the testing package.
+type TB interface {
+	Helper()
+	Log(args ...any)
+	Errorf(format string, args ...any)
+	Fatal(args ...any)
+	Fatalf(format string, args ...any)
+	Cleanup(fn func())
+}
+
+type tbWriter struct {
+	mu   sync.Mutex
+	tb   TB
+	done bool
+}
+
+func (w *tbWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// testing panics on Log once the test completed
+	if !w.done {
+		w.tb.Log(strings.TrimSuffix(string(p), "\n"))
+	}
+	return len(p), nil
+}
+
+// NewTB returns a logger writing through t.Log, so output is attributed to
+// the running test and only shown for failures or with -v. Entries carry
+// the caller of the log call, the t.Log location itself is the writer's.
+// Entries logged after the test completed are discarded.
+func NewTB(t TB, opts ...Option) *Logger {
+	w := &tbWriter{tb: t}
+	t.Cleanup(func() {
+		w.mu.Lock()
+		w.done = true
+		w.mu.Unlock()
+	})
+	return New(append([]Option{WithLevel(TraceLevel), WithPosition(w)}, opts...)...)
+}
+
+type failure struct {
+	mu    sync.Mutex
+	tb    TB
+	level Level
+	panic bool
+	done  bool
+}
+
+// WithFailOn marks t as failed whenever an entry at lvl or above is
+// logged, catching error logs a test would otherwise ignore. Entries
+// logged after the test completed are ignored.
+func WithFailOn(t TB, lvl Level) Option {
+	f := &failure{tb: t, level: lvl}
+	t.Cleanup(func() {
+		f.mu.Lock()
+		f.done = true
+		f.mu.Unlock()
+	})
+	return func(o *options) {
+		o.failure = f
+	}
+}
+
+// WithPanicOn panics whenever an entry at lvl or above is logged, for
+// code paths where a test failure alone would go unnoticed.
+func WithPanicOn(lvl Level) Option {
+	return func(o *options) {
+		o.failure = &failure{level: lvl, panic: true}
+	}
+}
+
+func (e *Entry) checkFailure() {
+	f := e.logger.opt.failure
+	if f == nil || e.Level < f.level {
+		return
+	}
+	msg := strings.TrimSuffix(e.Buf.String(), "\n")
+	if f.panic {
+		panic("logie: unexpected " + LevelMapping[e.Level] + " entry: " + msg)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// testing panics on Errorf once the test completed
+	if !f.done {
+		f.tb.Helper()
+		f.tb.Errorf("logie: unexpected %s entry: %s", LevelMapping[e.Level], msg)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeTB records what the helpers report, and panics like testing does
+// once cleanup ran.
+type fakeTB struct {
+	logs     []string
+	errors   []string
+	cleanups []func()
+	finished bool
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Log(args ...any) {
+	if f.finished {
+		panic("Log in goroutine after test has completed")
+	}
+	f.logs = append(f.logs, fmt.Sprint(args...))
+}
+
+func (f *fakeTB) Errorf(format string, args ...any) {
+	if f.finished {
+		panic("Errorf in goroutine after test has completed")
+	}
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Fatal(args ...any) { f.Errorf("%s", fmt.Sprint(args...)) }
+
+func (f *fakeTB) Fatalf(format string, args ...any) { f.Errorf(format, args...) }
+
+func (f *fakeTB) Cleanup(fn func()) { f.cleanups = append(f.cleanups, fn) }
+
+func (f *fakeTB) finish() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+	f.finished = true
+}
+
+func TestNewTB(t *testing.T) {
+	tb := &fakeTB{}
+	l := NewTB(tb, WithFormatter(&TextFormatter{IgnoreBasicFields: true}))
+	l.Debug("first")
+	tb.finish()
+	l.Info("after the test")
+
+	if len(tb.logs) != 1 || tb.logs[0] != "first" {
+		t.Errorf("logs = %q, want only the entry logged during the test", tb.logs)
+	}
+}
+
+func TestWithFailOn(t *testing.T) {
+	tests := []struct {
+		name  string
+		log   func(l *Logger)
+		fails int
+	}{
+		{"below the level", func(l *Logger) { l.Warn("careful") }, 0},
+		{"at the level", func(l *Logger) { l.Error("broken") }, 1},
+		{"above the level", func(l *Logger) { l.Panicf("%s", "stop") }, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := &fakeTB{}
+			l := NewTB(tb, WithFailOn(tb, ErrorLevel))
+			func() {
+				defer func() { recover() }()
+				tt.log(l)
+			}()
+			if len(tb.errors) != tt.fails {
+				t.Errorf("errors = %q, want %d", tb.errors, tt.fails)
+			}
+		})
+	}
+}
+
+func TestWithFailOnAfterTest(t *testing.T) {
+	tb := &fakeTB{}
+	l := New(WithPosition(&strings.Builder{}), WithFailOn(tb, ErrorLevel))
+	tb.finish()
+	// a goroutine outliving the test must not make testing panic
+	l.Error("late")
+	if len(tb.errors) != 0 {
+		t.Errorf("errors = %q, want none after the test", tb.errors)
+	}
+}
+
+func TestWithPanicOn(t *testing.T) {
+	l := New(WithPosition(&strings.Builder{}), WithPanicOn(WarnLevel))
+	l.Info("fine")
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "unexpected Warn entry") {
+			t.Errorf("recover() = %v, want the unexpected entry", r)
+		}
+	}()
+	l.Warn("careful")
+}
+
+func TestGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "text.golden")
+	entries := []*Entry{
+		NewEntry(InfoLevel, "started", nil),
+		NewEntry(ErrorLevel, "failed", Fields{"code": 7}),
+	}
+
+	t.Setenv(GoldenUpdateEnv, "1")
+	Golden(t, &JSONFormatter{}, entries, path)
+	t.Setenv(GoldenUpdateEnv, "")
+
+	tb := &fakeTB{}
+	Golden(tb, &JSONFormatter{}, entries, path)
+	if len(tb.errors) != 0 {
+		t.Fatalf("unchanged output reported: %q", tb.errors)
+	}
+
+	if err := os.WriteFile(path, []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	Golden(tb, &JSONFormatter{}, entries, path)
+	if len(tb.errors) != 1 {
+		t.Errorf("errors = %q, want the difference reported", tb.errors)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import "time"
+
+// DoneFunc completes a timed operation, fields are added to the completion
+// entry. A non-nil "error" field sets its status to "error".
+type DoneFunc func(fields ...Fields)
+
+// Timed starts timing msg, the returned DoneFunc logs its completion with
+// its latency, see Latency, and status at lvl.
+func (l *Logger) Timed(lvl Level, msg string) DoneFunc {
+	return l.timed(lvl, msg, l.now())
+}
+
+// TimedStart is like Timed but also logs when the operation starts.
+func (l *Logger) TimedStart(lvl Level, msg string) DoneFunc {
+	start := l.now()
+	l.WithFields(Fields{"status": "started"}).entry().write(lvl, FmtEmptySeparate, msg)
+	return l.timed(lvl, msg, start)
+}
+
+func (l *Logger) timed(lvl Level, msg string, start time.Time) DoneFunc {
+	return func(fields ...Fields) {
+		latency := Latency(start, l.now())
+		merged := Fields{"status": "ok"}
+		for _, f := range fields {
+			for k, v := range f {
+				merged[k] = v
+			}
+		}
+		if err, ok := merged["error"]; ok && err != nil {
+			merged["status"] = "error"
+		}
+		for k, v := range latency {
+			merged[k] = v
+		}
+
+		l.WithFields(merged).entry().write(lvl, FmtEmptySeparate, msg)
+	}
+}
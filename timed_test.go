@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimed(t *testing.T) {
+	tests := []struct {
+		name   string
+		start  bool
+		fields []Fields
+		want   []string
+	}{
+		{
+			name: "ok",
+			want: []string{"load latency=1s latency_ms=1000 status=ok\n"},
+		},
+		{
+			name:   "error",
+			fields: []Fields{{"error": errors.New("boom"), "rows": 3}},
+			want:   []string{"load error=boom latency=1s latency_ms=1000 rows=3 status=error\n"},
+		},
+		{
+			name:   "latency not overridden",
+			fields: []Fields{{"latency": "forever"}},
+			want:   []string{"load latency=1s latency_ms=1000 status=ok\n"},
+		},
+		{
+			name:  "started",
+			start: true,
+			want:  []string{"load status=started\n", "load latency=1s latency_ms=1000 status=ok\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &collector{}
+			now := time.Unix(0, 0)
+			l := New(WithPosition(out), WithFormatter(&TextFormatter{IgnoreBasicFields: true}),
+				WithClock(func() time.Time { return now }))
+			done := l.Timed(InfoLevel, "load")
+			if tt.start {
+				done = l.TimedStart(InfoLevel, "load")
+			}
+			now = now.Add(time.Second)
+			done(tt.fields...)
+			if got := out.got(); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("logged %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
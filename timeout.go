@@ -0,0 +1,29 @@
+package main
+
+import "time"
+
+type deadlineWriter interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// WithWriteTimeout bounds every write to outputs supporting write
+// deadlines, such as network connections and pipes. For other outputs
+// combine it with WithWriteWatchdog.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.writeTimeout = d
+	}
+}
+
+func (l *Logger) writeDeadline(lvl Level, p []byte) error {
+	w := l.destination(lvl)
+	dw, ok := w.(deadlineWriter)
+	if !ok || l.opt.writeTimeout <= 0 {
+		return writeFull(w, lvl, p)
+	}
+	if err := dw.SetWriteDeadline(time.Now().Add(l.opt.writeTimeout)); err != nil {
+		return writeFull(w, lvl, p)
+	}
+	defer dw.SetWriteDeadline(time.Time{})
+	return writeFull(w, lvl, p)
+}
@@ -0,0 +1,21 @@
+package main
+
+import "time"
+
+type zoneFormatter struct {
+	loc *time.Location
+	f   Formatter
+}
+
+// InLocation formats entries with f, their time converted to loc, e.g.
+// local time on the console while files and routes stay in UTC.
+func InLocation(loc *time.Location, f Formatter) Formatter {
+	return &zoneFormatter{loc: loc, f: f}
+}
+
+func (z *zoneFormatter) Format(e *Entry) error {
+	t := e.Time
+	e.Time = t.In(z.loc)
+	defer func() { e.Time = t }()
+	return z.f.Format(e)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TLSConfig describes the TLS setup of network sinks. With both CertFile
+// and KeyFile set the client authenticates itself (mTLS), the pair is
+// read again when the files change, checked at most every
+// ReloadInterval, so rotated certificates are picked up on the next
+// handshake without a restart.
+type TLSConfig struct {
+	// CAFile is a PEM bundle verifying the server, the system roots are
+	// used when empty.
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	// ServerName is sent as SNI and verified, it defaults to the host of
+	// the sink address.
+	ServerName string
+	// MinVersion defaults to TLS 1.2.
+	MinVersion         uint16
+	InsecureSkipVerify bool
+	ReloadInterval     time.Duration
+}
+
+// Build returns the tls.Config described by c.
+func (c *TLSConfig) Build() (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         c.ServerName,
+		MinVersion:         c.MinVersion,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("logie: no certificate found in %s", c.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, errors.New("logie: client certificate needs both CertFile and KeyFile")
+		}
+		kp := &keyPair{certFile: c.CertFile, keyFile: c.KeyFile, interval: c.ReloadInterval}
+		if err := kp.load(); err != nil {
+			return nil, err
+		}
+		cfg.GetClientCertificate = kp.get
+	}
+	return cfg, nil
+}
+
+// keyPair reloads a client certificate when its files are modified.
+type keyPair struct {
+	mu                sync.Mutex
+	certFile, keyFile string
+	interval          time.Duration
+	cert              *tls.Certificate
+	modTime           time.Time
+	checked           time.Time
+}
+
+func (kp *keyPair) load() error {
+	cert, err := tls.LoadX509KeyPair(kp.certFile, kp.keyFile)
+	if err != nil {
+		return err
+	}
+	kp.cert, kp.modTime = &cert, kp.lastModified()
+	return nil
+}
+
+func (kp *keyPair) lastModified() time.Time {
+	var latest time.Time
+	for _, path := range []string{kp.certFile, kp.keyFile} {
+		if st, err := os.Stat(path); err == nil && st.ModTime().After(latest) {
+			latest = st.ModTime()
+		}
+	}
+	return latest
+}
+
+func (kp *keyPair) get(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	if kp.interval > 0 && time.Since(kp.checked) >= kp.interval {
+		kp.checked = time.Now()
+		// a failed reload, e.g. while the files are being replaced, keeps
+		// the previous certificate
+		if kp.lastModified().After(kp.modTime) {
+			_ = kp.load()
+		}
+	}
+	return kp.cert, nil
+}
+
+// DialTLS connects to addr over TCP with TLS, the returned connection can
+// be used as an output.
+func DialTLS(addr string, c *TLSConfig) (net.Conn, error) {
+	cfg, err := c.Build()
+	if err != nil {
+		return nil, err
+	}
+	return tls.Dial("tcp", addr, cfg)
+}
+
+// openTLSSink understands the ca, cert, key, sni, min_version (1.0 to 1.3),
+// reload (a duration) and insecure query parameters, e.g.
+// "tls://collector:6514?ca=/etc/ca.pem&cert=/etc/c.pem&key=/etc/c.key".
+func openTLSSink(u *url.URL) (io.Writer, error) {
+	c, err := tlsConfigFromQuery(u.Query())
+	if err != nil {
+		return nil, err
+	}
+	return DialTLS(u.Host, c)
+}
+
+func tlsConfigFromQuery(q url.Values) (*TLSConfig, error) {
+	c := &TLSConfig{
+		CAFile:     q.Get("ca"),
+		CertFile:   q.Get("cert"),
+		KeyFile:    q.Get("key"),
+		ServerName: q.Get("sni"),
+	}
+	if v := q.Get("min_version"); v != "" {
+		versions := map[string]uint16{
+			"1.0": tls.VersionTLS10,
+			"1.1": tls.VersionTLS11,
+			"1.2": tls.VersionTLS12,
+			"1.3": tls.VersionTLS13,
+		}
+		ver, ok := versions[v]
+		if !ok {
+			return nil, fmt.Errorf("logie: invalid TLS version %q", v)
+		}
+		c.MinVersion = ver
+	}
+	if v := q.Get("reload"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("logie: invalid reload interval %q", v)
+		}
+		c.ReloadInterval = d
+	}
+	if v := q.Get("insecure"); v != "" {
+		insecure, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("logie: invalid insecure flag %q", v)
+		}
+		c.InsecureSkipVerify = insecure
+	}
+	return c, nil
+}
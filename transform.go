@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Transform rewrites the copy of an entry handled by a route.
+type Transform func(e *Entry)
+
+// RedactFields masks the values of the named fields.
+func RedactFields(keys ...string) Transform {
+	return func(e *Entry) {
+		for _, k := range keys {
+			if _, ok := e.Fields[k]; ok {
+				e.Fields[k] = redacted
+			}
+		}
+	}
+}
+
+// DropFields removes the named fields.
+func DropFields(keys ...string) Transform {
+	return func(e *Entry) {
+		for _, k := range keys {
+			delete(e.Fields, k)
+		}
+	}
+}
+
+// RenameField moves the value of field from to field to.
+func RenameField(from, to string) Transform {
+	return func(e *Entry) {
+		if v, ok := e.Fields[from]; ok {
+			delete(e.Fields, from)
+			e.Fields[to] = v
+		}
+	}
+}
+
+// copyEntry returns a copy of e sharing nothing mutable with it, so it
+// can be changed and formatted again while e is released to the pool.
+func (e *Entry) copyEntry() *Entry {
+	c := *e
+	c.Buf = new(bytes.Buffer)
+	c.Map = make(map[string]any, len(e.Map))
+	c.Args = append([]any(nil), e.Args...)
+	c.Fields = mergeFields(nil, e.Fields)
+	c.extra, c.ownFields = nil, true
+	return &c
+}
+
+func (r *Route) transform(e *Entry) (c *Entry, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			c, err = nil, newPanicError("route "+r.Name+" transform", v)
+		}
+	}()
+	c = e.copyEntry()
+	for _, t := range r.Transforms {
+		t(c)
+	}
+	f := r.Formatter
+	if f == nil {
+		f = e.logger.opt.formatter
+	}
+	if ferr := f.Format(c); ferr != nil {
+		if c.Buf.Len() == 0 {
+			return nil, ferr
+		}
+		// the entry was still encoded, e.g. under ReservedError
+		e.logger.reportError(fmt.Errorf("logie: route %s: %w", r.Name, ferr))
+	}
+	c.scanSecrets()
+	return c, nil
+}
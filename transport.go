@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+var defaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// defaultRedactParams are the query and body parameters always masked by
+// Transport.
+var defaultRedactParams = []string{"access_token", "api_key", "apikey", "client_secret", "password", "secret", "token", "X-Amz-Signature"}
+
+type TransportOption func(*transport)
+
+type transport struct {
+	rt      http.RoundTripper
+	logger  *Logger
+	headers []string
+	redact  map[string]struct{}
+	jsonKey *regexp.Regexp
+	body    int
+	retries int
+}
+
+// WithHeaderCapture logs the values of the named request and response
+// headers.
+func WithHeaderCapture(names ...string) TransportOption {
+	return func(t *transport) {
+		for _, name := range names {
+			t.headers = append(t.headers, textproto.CanonicalMIMEHeaderKey(name))
+		}
+	}
+}
+
+// WithRedactHeaders masks the named headers when captured, in addition to
+// the credential headers always masked. The names also mask query
+// parameters and the keys of captured JSON and form bodies.
+func WithRedactHeaders(names ...string) TransportOption {
+	return func(t *transport) {
+		for _, name := range names {
+			t.redact[textproto.CanonicalMIMEHeaderKey(name)] = struct{}{}
+		}
+	}
+}
+
+// WithBodyCapture logs up to limit bytes of request and response bodies.
+// The response body is captured as the caller reads it, the request is
+// then logged when the body is closed, so streaming responses are not
+// held back.
+func WithBodyCapture(limit int) TransportOption {
+	return func(t *transport) {
+		t.body = limit
+	}
+}
+
+// WithTransportRetries retries idempotent requests failing with a
+// network error up to n times.
+func WithTransportRetries(n int) TransportOption {
+	return func(t *transport) {
+		t.retries = n
+	}
+}
+
+// Transport wraps rt to log every outbound request with its method, URL,
+// status, latency and retry count.
+func Transport(rt http.RoundTripper, l *Logger, opts ...TransportOption) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	t := &transport{rt: rt, logger: l, redact: make(map[string]struct{})}
+	for _, name := range defaultRedactHeaders {
+		t.redact[name] = struct{}{}
+	}
+	for _, name := range defaultRedactParams {
+		t.redact[textproto.CanonicalMIMEHeaderKey(name)] = struct{}{}
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	names := make([]string, 0, len(t.redact))
+	for name := range t.redact {
+		names = append(names, regexp.QuoteMeta(name))
+	}
+	t.jsonKey = regexp.MustCompile(`(?i)("(?:` + strings.Join(names, "|") + `)"\s*:\s*)(?:"(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+	return t
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fields := Fields{
+		"http.method": req.Method,
+		"http.url":    t.redactURL(req.URL),
+	}
+	t.captureHeaders(fields, "http.request.header.", req.Header)
+	if t.body > 0 && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			fields["http.request.body"] = t.redactBody(req.Header, readLimited(body, t.body))
+			_ = body.Close()
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.rt.RoundTrip(req)
+	attempt := 0
+	for ; err != nil && attempt < t.retries && t.retryable(req); attempt++ {
+		retry := req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, berr := req.GetBody()
+			if berr != nil {
+				break
+			}
+			retry.Body = body
+		}
+		resp, err = t.rt.RoundTrip(retry)
+	}
+	fields["http.latency"] = time.Since(start).String()
+	fields["http.retries"] = attempt
+
+	lvl := InfoLevel
+	switch {
+	case err != nil:
+		lvl = ErrorLevel
+		fields["error"] = err.Error()
+	case resp.StatusCode >= 500:
+		lvl = ErrorLevel
+	case resp.StatusCode >= 400:
+		lvl = WarnLevel
+	}
+	if resp != nil {
+		fields["http.status"] = resp.StatusCode
+		t.captureHeaders(fields, "http.response.header.", resp.Header)
+		// a 101 body is the upgraded connection and must stay an
+		// io.ReadWriteCloser
+		if t.body > 0 && resp.Body != nil && resp.Body != http.NoBody && resp.StatusCode != http.StatusSwitchingProtocols {
+			header := resp.Header
+			resp.Body = &capturedBody{ReadCloser: resp.Body, limit: t.body, log: func(body string) {
+				fields["http.response.body"] = t.redactBody(header, body)
+				t.logger.WithFields(fields).entry().write(lvl, FmtEmptySeparate, "http request")
+			}}
+			return resp, err
+		}
+	}
+
+	t.logger.WithFields(fields).entry().write(lvl, FmtEmptySeparate, "http request")
+	return resp, err
+}
+
+// capturedBody keeps the first limit bytes read from a response body and
+// logs them on Close.
+type capturedBody struct {
+	io.ReadCloser
+	limit int
+	buf   bytes.Buffer
+	log   func(body string)
+	once  sync.Once
+}
+
+func (b *capturedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := b.limit - b.buf.Len(); room > 0 {
+		if room > n {
+			room = n
+		}
+		b.buf.Write(p[:room])
+	}
+	return n, err
+}
+
+func (b *capturedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.log(b.buf.String()) })
+	return err
+}
+
+func (t *transport) retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	}
+	return false
+}
+
+func (t *transport) captureHeaders(fields Fields, prefix string, h http.Header) {
+	for _, name := range t.headers {
+		values, ok := h[name]
+		if !ok {
+			continue
+		}
+		if _, ok := t.redact[name]; ok {
+			fields[prefix+strings.ToLower(name)] = redacted
+			continue
+		}
+		fields[prefix+strings.ToLower(name)] = strings.Join(values, ", ")
+	}
+}
+
+func (t *transport) redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Redacted()
+	}
+	c := *u
+	c.RawQuery = t.redactQuery(u.RawQuery)
+	return c.Redacted()
+}
+
+// redactQuery masks the values of redacted parameters in a query string
+// or form body, keeping the order of the others.
+func (t *transport) redactQuery(q string) string {
+	params := strings.Split(q, "&")
+	for i, param := range params {
+		key, _, found := strings.Cut(param, "=")
+		if !found {
+			continue
+		}
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if _, ok := t.redact[textproto.CanonicalMIMEHeaderKey(key)]; ok {
+			params[i] = param[:strings.IndexByte(param, '=')+1] + redacted
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// redactBody masks redacted keys in JSON and form bodies. Truncated JSON
+// is handled too, other bodies are returned unchanged.
+func (t *transport) redactBody(h http.Header, body string) string {
+	mediaType, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		return t.redactQuery(body)
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return t.jsonKey.ReplaceAllString(body, `${1}"`+redacted+`"`)
+	}
+	return body
+}
+
+func readLimited(r io.Reader, limit int) string {
+	buf := make([]byte, limit)
+	n, _ := io.ReadFull(r, buf)
+	return string(buf[:n])
+}
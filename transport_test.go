@@ -0,0 +1,163 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// upgradedConn stands in for the connection of a 101 response body.
+type upgradedConn struct{ io.Reader }
+
+func (upgradedConn) Write(p []byte) (int, error) { return len(p), nil }
+func (upgradedConn) Close() error                { return nil }
+
+func TestTransportRedacts(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     func() *http.Request
+		resp    *http.Response
+		opts    []TransportOption
+		field   string
+		want    string
+		notWant string
+	}{
+		{
+			name: "query token",
+			req: func() *http.Request {
+				r, _ := http.NewRequest("GET", "http://example.com/a?page=2&access_token=s3cr3t&q=x", nil)
+				return r
+			},
+			field:   "http.url",
+			want:    "http://example.com/a?page=2&access_token=[REDACTED]&q=x",
+			notWant: "s3cr3t",
+		},
+		{
+			name: "query parameter from the header list",
+			req: func() *http.Request {
+				r, _ := http.NewRequest("GET", "http://example.com/?X-Session=s3cr3t", nil)
+				return r
+			},
+			opts:  []TransportOption{WithRedactHeaders("x-session")},
+			field: "http.url",
+			want:  "http://example.com/?X-Session=[REDACTED]",
+		},
+		{
+			name: "json request body",
+			req: func() *http.Request {
+				r, _ := http.NewRequest("POST", "http://example.com/login", strings.NewReader(`{"user":"bob","password":"s3cr3t"}`))
+				r.Header.Set("Content-Type", "application/json; charset=utf-8")
+				return r
+			},
+			opts:  []TransportOption{WithBodyCapture(100)},
+			field: "http.request.body",
+			want:  `{"user":"bob","password":"[REDACTED]"}`,
+		},
+		{
+			name: "truncated json body",
+			req: func() *http.Request {
+				r, _ := http.NewRequest("POST", "http://example.com/login", strings.NewReader(`{"token":"s3cr3t-and-more","user":"bob"}`))
+				r.Header.Set("Content-Type", "application/json")
+				return r
+			},
+			opts:    []TransportOption{WithBodyCapture(15)},
+			field:   "http.request.body",
+			want:    `{"token":"[REDACTED]"`,
+			notWant: "s3cr3t",
+		},
+		{
+			name: "form body",
+			req: func() *http.Request {
+				r, _ := http.NewRequest("POST", "http://example.com/oauth", strings.NewReader("grant=code&client_secret=s3cr3t"))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return r
+			},
+			opts:  []TransportOption{WithBodyCapture(100)},
+			field: "http.request.body",
+			want:  "grant=code&client_secret=[REDACTED]",
+		},
+		{
+			name: "json response body",
+			req: func() *http.Request {
+				r, _ := http.NewRequest("GET", "http://example.com/token", nil)
+				return r
+			},
+			resp: &http.Response{
+				StatusCode: 200,
+				Header:     http.Header{"Content-Type": {"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(`{"access_token": "s3cr3t", "expires": 60}`)),
+			},
+			opts:  []TransportOption{WithBodyCapture(100)},
+			field: "http.response.body",
+			want:  `{"access_token": "[REDACTED]", "expires": 60}`,
+		},
+		{
+			name: "plain body untouched",
+			req: func() *http.Request {
+				r, _ := http.NewRequest("POST", "http://example.com/", strings.NewReader("password=kept"))
+				r.Header.Set("Content-Type", "text/plain")
+				return r
+			},
+			opts:  []TransportOption{WithBodyCapture(100)},
+			field: "http.request.body",
+			want:  "password=kept",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &lockedBuilder{}
+			l := New(WithPosition(out), WithFormatter(&JSONFormatter{}))
+			rt := Transport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				if tt.resp != nil {
+					return tt.resp, nil
+				}
+				return &http.Response{StatusCode: 204, Header: http.Header{}, Body: http.NoBody}, nil
+			}), l, tt.opts...)
+			resp, err := rt.RoundTrip(tt.req())
+			if err != nil {
+				t.Fatal(err)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+
+			var entry map[string]any
+			if err := decodeJSON([]byte(out.String()), &entry); err != nil {
+				t.Fatalf("decode %q: %v", out.String(), err)
+			}
+			if got := entry[tt.field]; got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.field, got, tt.want)
+			}
+			if tt.notWant != "" && strings.Contains(out.String(), tt.notWant) {
+				t.Errorf("output %q contains %q", out.String(), tt.notWant)
+			}
+		})
+	}
+}
+
+func TestTransportKeepsUpgradedBody(t *testing.T) {
+	out := &lockedBuilder{}
+	l := New(WithPosition(out), WithFormatter(&JSONFormatter{}))
+	rt := Transport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusSwitchingProtocols,
+			Header:     http.Header{"Upgrade": {"websocket"}},
+			Body:       upgradedConn{strings.NewReader("frames")},
+		}, nil
+	}), l, WithBodyCapture(100))
+	req, _ := http.NewRequest("GET", "http://example.com/ws", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resp.Body.(io.ReadWriteCloser); !ok {
+		t.Fatalf("101 body is %T, want an io.ReadWriteCloser", resp.Body)
+	}
+	if !strings.Contains(out.String(), `"http.status":101`) {
+		t.Errorf("upgrade not logged on return: %q", out.String())
+	}
+}
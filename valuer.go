@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Valuer is implemented by types controlling their structured form:
+// formatters log the fields returned by LogieValue instead of the value,
+// as a nested object in JSON and as dotted keys in text.
+type Valuer interface {
+	LogieValue() Fields
+}
+
+// maxValuerDepth stops Valuers returning themselves or each other.
+const maxValuerDepth = 8
+
+// resolveValuer returns v with Valuers replaced by their fields, nested
+// ones included.
+func resolveValuer(v any) any {
+	return resolveValuerDepth(v, 0)
+}
+
+func resolveValuerDepth(v any, depth int) any {
+	val, ok := v.(Valuer)
+	if !ok || isNil(v) {
+		return v
+	}
+	if depth >= maxValuerDepth {
+		return fmt.Sprintf("%%!v(DEPTH=%T)", v)
+	}
+	fields := val.LogieValue()
+	resolved := make(Fields, len(fields))
+	for k, fv := range fields {
+		resolved[k] = resolveValuerDepth(fv, depth+1)
+	}
+	return resolved
+}
+
+// writeTextField writes " key=value", flattening Valuers into dotted
+// keys.
+func writeTextField(buf *bytes.Buffer, key string, v any) {
+	if _, ok := v.(Valuer); ok {
+		if fields, ok := resolveValuer(v).(Fields); ok {
+			writeTextFields(buf, key, fields)
+			return
+		}
+	}
+	buf.WriteString(fmt.Sprintf(" %s=%v", key, v))
+}
+
+func writeTextFields(buf *bytes.Buffer, prefix string, fields Fields) {
+	for _, k := range fields.keys() {
+		if nested, ok := fields[k].(Fields); ok {
+			writeTextFields(buf, prefix+"."+k, nested)
+			continue
+		}
+		buf.WriteString(fmt.Sprintf(" %s.%s=%v", prefix, k, fields[k]))
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	volumeBucket    = time.Minute
+	volumeRetention = time.Hour
+)
+
+// VolumeItem is a message or logger of a VolumeReport. Counts are upper
+// bounds, they may overestimate by at most Error bytes.
+type VolumeItem struct {
+	Key     string
+	Entries uint64
+	Bytes   uint64
+	Error   uint64
+}
+
+type VolumeReport struct {
+	Window   time.Duration
+	Entries  uint64
+	Bytes    uint64
+	Messages []VolumeItem
+	Loggers  []VolumeItem
+}
+
+type volumeTracker struct {
+	mu      sync.Mutex
+	size    int
+	buckets []*volumeBucketStats
+}
+
+type volumeBucketStats struct {
+	start    time.Time
+	entries  uint64
+	bytes    uint64
+	messages spaceSaving
+	loggers  spaceSaving
+}
+
+// spaceSaving keeps the heaviest keys by bytes in a bounded map, a new
+// key evicts the lightest one and inherits its bytes as error.
+type spaceSaving map[string]*VolumeItem
+
+func (s spaceSaving) add(key string, size, n int) {
+	if it, ok := s[key]; ok {
+		it.Entries++
+		it.Bytes += uint64(n)
+		return
+	}
+	var evicted VolumeItem
+	if len(s) >= size {
+		// "" is a valid key, the root logger has no name
+		var (
+			min   string
+			found bool
+		)
+		for k, it := range s {
+			if !found || it.Bytes < s[min].Bytes {
+				min, found = k, true
+			}
+		}
+		evicted = *s[min]
+		delete(s, min)
+	}
+	// key may alias bytes of a zero-copy Write, keep a copy
+	key = string([]byte(key))
+	s[key] = &VolumeItem{
+		Key:     key,
+		Entries: evicted.Entries + 1,
+		Bytes:   evicted.Bytes + uint64(n),
+		Error:   evicted.Bytes,
+	}
+}
+
+// WithVolumeReport tracks the size entries of the heaviest messages and
+// loggers for the last hour, see Logger.VolumeReport.
+func WithVolumeReport(size int) Option {
+	return func(o *options) {
+		if size <= 0 {
+			o.volume = nil
+			return
+		}
+		o.volume = &volumeTracker{size: size}
+	}
+}
+
+func (e *Entry) trackVolume() {
+	v := e.logger.opt.volume
+	if v == nil {
+		return
+	}
+	msg := messageKey(e.Format, e.Args)
+	n := e.Buf.Len()
+	start := e.Time.Truncate(volumeBucket)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	var b *volumeBucketStats
+	if len(v.buckets) > 0 && !v.buckets[len(v.buckets)-1].start.Before(start) {
+		b = v.buckets[len(v.buckets)-1]
+	} else {
+		b = &volumeBucketStats{start: start, messages: spaceSaving{}, loggers: spaceSaving{}}
+		v.buckets = append(v.buckets, b)
+		i := 0
+		for start.Sub(v.buckets[i].start) >= volumeRetention {
+			i++
+		}
+		v.buckets = v.buckets[i:]
+	}
+	b.entries++
+	b.bytes += uint64(n)
+	b.messages.add(msg, v.size, n)
+	b.loggers.add(e.logger.name, v.size, n)
+}
+
+// VolumeReport returns the heaviest messages and loggers by bytes over
+// the last window, up to one hour, to find the noisiest log statements.
+// It needs WithVolumeReport.
+func (l *Logger) VolumeReport(window time.Duration) VolumeReport {
+	if window > volumeRetention {
+		window = volumeRetention
+	}
+	r := VolumeReport{Window: window}
+	v := l.opt.volume
+	if v == nil {
+		return r
+	}
+	since := l.now().Add(-window).Truncate(volumeBucket)
+	messages, loggers := map[string]*VolumeItem{}, map[string]*VolumeItem{}
+
+	v.mu.Lock()
+	for _, b := range v.buckets {
+		if b.start.Before(since) {
+			continue
+		}
+		r.Entries += b.entries
+		r.Bytes += b.bytes
+		mergeVolume(messages, b.messages)
+		mergeVolume(loggers, b.loggers)
+	}
+	v.mu.Unlock()
+
+	r.Messages = topVolume(messages, v.size)
+	r.Loggers = topVolume(loggers, v.size)
+	return r
+}
+
+func mergeVolume(dst map[string]*VolumeItem, src spaceSaving) {
+	for k, it := range src {
+		if d, ok := dst[k]; ok {
+			d.Entries += it.Entries
+			d.Bytes += it.Bytes
+			d.Error += it.Error
+		} else {
+			c := *it
+			dst[k] = &c
+		}
+	}
+}
+
+func topVolume(items map[string]*VolumeItem, n int) []VolumeItem {
+	top := make([]VolumeItem, 0, len(items))
+	for _, it := range items {
+		top = append(top, *it)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Bytes != top[j].Bytes {
+			return top[i].Bytes > top[j].Bytes
+		}
+		return top[i].Key < top[j].Key
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
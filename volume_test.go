@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestVolumeReport(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		log    func(l *Logger, advance func(time.Duration))
+		window time.Duration
+		want   VolumeReport
+	}{
+		{
+			name: "grouped by format and logger",
+			size: 5,
+			log: func(l *Logger, _ func(time.Duration)) {
+				l.Infof("user %d", 1)
+				l.Infof("user %d", 22)
+				l.Named("db").Info("query")
+			},
+			window: time.Minute,
+			want: VolumeReport{
+				Window: time.Minute, Entries: 3, Bytes: 7 + 8 + 16,
+				Messages: []VolumeItem{{Key: "query", Entries: 1, Bytes: 16}, {Key: "user %d", Entries: 2, Bytes: 15}},
+				Loggers:  []VolumeItem{{Key: "db", Entries: 1, Bytes: 16}, {Key: "", Entries: 2, Bytes: 15}},
+			},
+		},
+		{
+			name: "evicted keys carry their bytes as error",
+			size: 1,
+			log: func(l *Logger, _ func(time.Duration)) {
+				l.Info("aaaa")
+				l.Info("b")
+			},
+			window: time.Minute,
+			want: VolumeReport{
+				Window: time.Minute, Entries: 2, Bytes: 5 + 2,
+				Messages: []VolumeItem{{Key: "b", Entries: 2, Bytes: 7, Error: 5}},
+				Loggers:  []VolumeItem{{Key: "", Entries: 2, Bytes: 7}},
+			},
+		},
+		{
+			name: "older buckets outside the window",
+			size: 5,
+			log: func(l *Logger, advance func(time.Duration)) {
+				l.Info("old")
+				advance(10 * time.Minute)
+				l.Info("new")
+			},
+			window: 5 * time.Minute,
+			want: VolumeReport{
+				Window: 5 * time.Minute, Entries: 1, Bytes: 4,
+				Messages: []VolumeItem{{Key: "new", Entries: 1, Bytes: 4}},
+				Loggers:  []VolumeItem{{Key: "", Entries: 1, Bytes: 4}},
+			},
+		},
+		{
+			name: "window capped to the retention",
+			size: 5,
+			log: func(l *Logger, advance func(time.Duration)) {
+				l.Info("expired")
+				advance(2 * time.Hour)
+				l.Info("new")
+			},
+			window: 24 * time.Hour,
+			want: VolumeReport{
+				Window: time.Hour, Entries: 1, Bytes: 4,
+				Messages: []VolumeItem{{Key: "new", Entries: 1, Bytes: 4}},
+				Loggers:  []VolumeItem{{Key: "", Entries: 1, Bytes: 4}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			l := New(WithPosition(&collector{}), WithFormatter(&TextFormatter{IgnoreBasicFields: true}),
+				WithVolumeReport(tt.size), WithClock(func() time.Time { return now }))
+			tt.log(l, func(d time.Duration) { now = now.Add(d) })
+			if got := l.VolumeReport(tt.window); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("VolumeReport() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVolumeReportDisabled(t *testing.T) {
+	l := New(WithPosition(&collector{}), WithVolumeReport(0))
+	l.Info("x")
+	if got := l.VolumeReport(time.Minute); got.Entries != 0 || got.Messages != nil {
+		t.Errorf("VolumeReport() = %+v without tracking", got)
+	}
+}
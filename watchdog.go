@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+var errWriteBlocked = errors.New("logie: write to output blocked")
+
+type watchdog struct {
+	threshold time.Duration
+	stuck     int32
+}
+
+// WithOnError registers fn to be notified of errors the logger cannot
+// return to its caller, such as failed or blocked writes.
+func WithOnError(fn func(err error)) Option {
+	return func(o *options) {
+		o.onError = fn
+	}
+}
+
+// WithFallback sets the writer used while the output is blocked, see
+// WithWriteWatchdog.
+func WithFallback(w io.Writer) Option {
+	return func(o *options) {
+		o.fallback = w
+	}
+}
+
+// WithWriteWatchdog stops waiting for a write to the output after
+// threshold: the entry goes to the fallback writer, OnError is notified
+// and later entries skip the output until the blocked write returns.
+func WithWriteWatchdog(threshold time.Duration) Option {
+	return func(o *options) {
+		o.watchdog = &watchdog{threshold: threshold}
+	}
+}
+
+func (l *Logger) reportError(err error) {
+	if fn := l.opt.onError; fn != nil {
+		fn(err)
+	}
+}
+
+func (l *Logger) writePosition(lvl Level, p []byte) error {
+	wd := l.opt.watchdog
+	if wd == nil {
+		return l.writeDeadline(lvl, p)
+	}
+	if atomic.LoadInt32(&wd.stuck) == 1 {
+		return l.writeFallback(lvl, p)
+	}
+
+	// p is owned by a pooled entry, the write may outlive this call
+	buf := append([]byte(nil), p...)
+	done := make(chan error, 1)
+	go func() {
+		done <- l.writeDeadline(lvl, buf)
+	}()
+
+	t := time.NewTimer(wd.threshold)
+	defer t.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-t.C:
+	}
+
+	atomic.StoreInt32(&wd.stuck, 1)
+	go func() {
+		<-done
+		atomic.StoreInt32(&wd.stuck, 0)
+	}()
+	l.reportError(fmt.Errorf("%w for more than %s", errWriteBlocked, wd.threshold))
+	return l.writeFallback(lvl, p)
+}
+
+func (l *Logger) writeFallback(lvl Level, p []byte) error {
+	if l.opt.fallback == nil {
+		return errWriteBlocked
+	}
+	return writeFull(l.opt.fallback, lvl, p)
+}